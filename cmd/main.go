@@ -9,18 +9,33 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/citadel-corp/segokuning-social-app/internal/admin"
+	"github.com/citadel-corp/segokuning-social-app/internal/audit"
+	"github.com/citadel-corp/segokuning-social-app/internal/comments"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/auth"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/jwt"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/notifier"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/storage"
 	"github.com/citadel-corp/segokuning-social-app/internal/image"
+	"github.com/citadel-corp/segokuning-social-app/internal/loginattempt"
 	"github.com/citadel-corp/segokuning-social-app/internal/posts"
+	"github.com/citadel-corp/segokuning-social-app/internal/reactions"
+	"github.com/citadel-corp/segokuning-social-app/internal/reports"
+	"github.com/citadel-corp/segokuning-social-app/internal/session"
+	"github.com/citadel-corp/segokuning-social-app/internal/twofactor"
 	"github.com/citadel-corp/segokuning-social-app/internal/user"
 	userfriends "github.com/citadel-corp/segokuning-social-app/internal/user_friends"
+	"github.com/citadel-corp/segokuning-social-app/internal/verification"
 	"github.com/gorilla/mux"
 	"github.com/lmittmann/tint"
 )
@@ -56,9 +71,128 @@ func main() {
 	// 	os.Exit(1)
 	// }
 
-	// initialize user domain
+	// load token signing keys, tokens are signed with JWT_SECRET without them
+	if signingKeyFile := os.Getenv("JWT_SIGNING_KEY_FILE"); signingKeyFile != "" {
+		var verificationKeyFiles []string
+		if files := os.Getenv("JWT_VERIFICATION_KEY_FILES"); files != "" {
+			verificationKeyFiles = strings.Split(files, ",")
+		}
+		// tokens signed with JWT_SECRET before the switch are accepted until JWT_SECRET_ACCEPTED_UNTIL
+		var secretAcceptedUntil time.Time
+		if until := os.Getenv("JWT_SECRET_ACCEPTED_UNTIL"); until != "" {
+			secretAcceptedUntil, err = time.Parse(time.RFC3339, until)
+			if err != nil {
+				slog.Error(fmt.Sprintf("Cannot parse JWT_SECRET_ACCEPTED_UNTIL: %v", err))
+				os.Exit(1)
+			}
+		}
+		err = jwt.LoadKeys(signingKeyFile, verificationKeyFiles, secretAcceptedUntil)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Cannot load JWT keys: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	// initialize session domain
 	userRepository := user.NewRepository(db)
-	userService := user.NewService(userRepository)
+	sessionRepository := session.NewRepository(db)
+	sessionService := session.NewService(sessionRepository, user.RolesFunc(userRepository))
+	sessionHandler := session.NewHandler(sessionService)
+	middleware.SetSessionValidator(sessionService.IsActive)
+
+	// initialize image domain
+	var (
+		blobStorage  storage.Storage
+		localStorage *storage.Local
+	)
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "local":
+		storageSecret := os.Getenv("STORAGE_LOCAL_SECRET")
+		if storageSecret == "" {
+			slog.Error("STORAGE_LOCAL_SECRET must be set to sign the local storage upload URLs")
+			os.Exit(1)
+		}
+		localStorage, err = storage.NewLocal(getEnv("STORAGE_LOCAL_DIR", "uploads"), getEnv("STORAGE_LOCAL_BASE_URL", "http://localhost:8080"),
+			storageSecret)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Cannot create local storage: %v", err))
+			os.Exit(1)
+		}
+		blobStorage = localStorage
+	case "", "s3":
+		sess, err := awssession.NewSession(&aws.Config{
+			Region:      aws.String(os.Getenv("S3_REGION")),
+			Credentials: credentials.NewStaticCredentials(os.Getenv("S3_ID"), os.Getenv("S3_SECRET_KEY"), ""),
+		})
+		if err != nil {
+			slog.Error(fmt.Sprintf("Cannot create AWS session: %v", err))
+			os.Exit(1)
+		}
+		blobStorage = storage.NewS3(sess, os.Getenv("S3_BUCKET_NAME"))
+	default:
+		slog.Error(fmt.Sprintf("Unknown storage driver %q", driver))
+		os.Exit(1)
+	}
+	imageRepository := image.NewRepository(db)
+	dailyUploadCount, err := strconv.Atoi(getEnv("IMAGE_DAILY_UPLOAD_COUNT", "50"))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Invalid IMAGE_DAILY_UPLOAD_COUNT: %v", err))
+		os.Exit(1)
+	}
+	dailyUploadBytes, err := strconv.ParseInt(getEnv("IMAGE_DAILY_UPLOAD_BYTES", "104857600"), 10, 64)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Invalid IMAGE_DAILY_UPLOAD_BYTES: %v", err))
+		os.Exit(1)
+	}
+	imageService := image.NewService(imageRepository, blobStorage, image.Quota{
+		DailyCount: dailyUploadCount,
+		DailyBytes: dailyUploadBytes,
+	})
+	imageHandler := image.NewHandler(imageService)
+
+	// initialize verification domain
+	var codeNotifier notifier.Notifier
+	// codes are secrets, the driver that logs them is only picked implicitly outside production
+	switch driver := os.Getenv("NOTIFIER"); driver {
+	case "log":
+		codeNotifier = notifier.NewLog()
+	case "file":
+		codeNotifier = notifier.NewFile(getEnv("NOTIFIER_FILE", "notifications.log"))
+	case "":
+		if os.Getenv("ENV") == "production" {
+			slog.Error("NOTIFIER must be set to log or file in production")
+			os.Exit(1)
+		}
+		slog.Warn("NOTIFIER is not set, verification codes are written to the service log")
+		codeNotifier = notifier.NewLog()
+	default:
+		slog.Error(fmt.Sprintf("Unknown notifier %q", driver))
+		os.Exit(1)
+	}
+	verificationRepository := verification.NewRepository(db)
+	verificationService := verification.NewService(verificationRepository, codeNotifier)
+
+	// initialize audit domain
+	auditRepository := audit.NewRepository(db)
+	auditService := audit.NewService(auditRepository)
+
+	// initialize login attempt domain
+	loginAttemptRepository := loginattempt.NewRepository(db)
+	loginAttemptService := loginattempt.NewService(loginAttemptRepository)
+
+	// initialize two-factor domain
+	twoFactorRepository := twofactor.NewRepository(db)
+	twoFactorService := twofactor.NewService(twoFactorRepository)
+
+	// initialize user domain
+	switch policy := os.Getenv("UNVERIFIED_LOGIN"); policy {
+	case "", user.UnverifiedLoginFlag, user.UnverifiedLoginBlock:
+	default:
+		slog.Error(fmt.Sprintf("Unknown unverified login policy %q", policy))
+		os.Exit(1)
+	}
+	userService := user.NewService(userRepository, sessionService, imageService, verificationService, auditService,
+		loginAttemptService, twoFactorService)
 	userHandler := user.NewHandler(userService)
 
 	// initialize user friends domain
@@ -66,31 +200,49 @@ func main() {
 	userFriendsService := userfriends.NewService(userFriendsRepository, userRepository)
 	userFriendsHandler := userfriends.NewHandler(userFriendsService)
 
-	// initialize image domain
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(os.Getenv("S3_REGION")),
-		Credentials: credentials.NewStaticCredentials(os.Getenv("S3_ID"), os.Getenv("S3_SECRET_KEY"), ""),
-	})
-	if err != nil {
-		slog.Error(fmt.Sprintf("Cannot create AWS session: %v", err))
-		os.Exit(1)
-	}
-	imageService := image.NewService(sess)
-	imageHandler := image.NewHandler(imageService)
+	// initialize reactions domain
+	reactionsRepository := reactions.NewRepository(db)
+
+	// initialize comments domain
+	commentsRepository := comments.NewRepository(db)
+	commentsService := comments.NewService(commentsRepository, userFriendsRepository, reactionsRepository)
+	commentsHandler := comments.NewHandler(commentsService)
 
 	// initialize posts domain
 	postsRepository := posts.NewRepository(db)
-	postsService := posts.NewService(postsRepository, userFriendsRepository)
+	postsService := posts.NewService(postsRepository, userFriendsRepository, reactionsRepository, commentsRepository, imageService)
 	postsHandler := posts.NewHandler(postsService)
 
+	// initialize reports domain
+	reportHideThreshold, err := strconv.Atoi(getEnv("REPORT_HIDE_THRESHOLD", "5"))
+	if err != nil || reportHideThreshold < 0 {
+		slog.Error(fmt.Sprintf("Invalid REPORT_HIDE_THRESHOLD: %q", os.Getenv("REPORT_HIDE_THRESHOLD")))
+		os.Exit(1)
+	}
+	reportsRepository := reports.NewRepository(db)
+	reportsService := reports.NewService(reportsRepository, reportHideThreshold)
+	reportsHandler := reports.NewHandler(reportsService)
+
+	// initialize admin domain
+	adminRepository := admin.NewRepository(db)
+	adminService := admin.NewService(adminRepository, userRepository, postsRepository, reportsRepository, sessionService)
+	adminHandler := admin.NewHandler(adminService)
+
 	r := mux.NewRouter()
 	r.Use(middleware.Logging)
 	r.Use(middleware.PanicRecoverer)
+	r.Use(middleware.ClientInfo)
 
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "text")
 		io.WriteString(w, "Service ready")
 	})
+	r.HandleFunc("/.well-known/jwks.json", jwt.JWKSHandler).Methods(http.MethodGet)
+
+	if localStorage != nil {
+		r.PathPrefix(storage.LocalPathPrefix).Handler(localStorage.Handler()).Methods(http.MethodGet, http.MethodHead)
+		r.PathPrefix(storage.LocalPathPrefix).Handler(localStorage.UploadHandler(imageService.AcceptsUpload)).Methods(http.MethodPut)
+	}
 
 	v1 := r.PathPrefix("/v1").Subrouter()
 
@@ -98,26 +250,91 @@ func main() {
 	ur := v1.PathPrefix("/user").Subrouter()
 	ur.HandleFunc("/register", userHandler.CreateUser).Methods(http.MethodPost)
 	ur.HandleFunc("/login", userHandler.Login).Methods(http.MethodPost)
+	ur.HandleFunc("/login/2fa", userHandler.LoginTwoFactor).Methods(http.MethodPost)
+	ur.HandleFunc("/login/verify", userHandler.LoginVerify).Methods(http.MethodPost)
+	ur.HandleFunc("/2fa/enroll", middleware.Authorized(userHandler.EnrollTwoFactor)).Methods(http.MethodPost)
+	ur.HandleFunc("/2fa/confirm", middleware.Authorized(userHandler.ConfirmTwoFactor)).Methods(http.MethodPost)
+	ur.HandleFunc("/2fa/disable", middleware.Authorized(userHandler.DisableTwoFactor)).Methods(http.MethodPost)
+	ur.HandleFunc("/token/refresh", sessionHandler.Refresh).Methods(http.MethodPost)
+	ur.HandleFunc("/password/forgot", userHandler.ForgotPassword).Methods(http.MethodPost)
+	ur.HandleFunc("/password/reset", userHandler.ResetPassword).Methods(http.MethodPost)
+	ur.HandleFunc("/password", middleware.Authorized(userHandler.ChangePassword)).Methods(http.MethodPut)
+	ur.HandleFunc("/logout", middleware.Authorized(sessionHandler.Logout)).Methods(http.MethodPost)
 	ur.HandleFunc("/link", middleware.Authorized(userHandler.LinkEmail)).Methods(http.MethodPost)
 	ur.HandleFunc("/link/email", middleware.Authorized(userHandler.LinkEmail)).Methods(http.MethodPost)
 	ur.HandleFunc("/link/phone", middleware.Authorized(userHandler.LinkPhoneNumber)).Methods(http.MethodPost)
-	ur.HandleFunc("", middleware.Authorized(userHandler.Update)).Methods(http.MethodPatch)
+	ur.HandleFunc("/link/email/verify", middleware.Authorized(userHandler.VerifyEmail)).Methods(http.MethodPost)
+	ur.HandleFunc("/link/phone/verify", middleware.Authorized(userHandler.VerifyPhoneNumber)).Methods(http.MethodPost)
+	ur.HandleFunc("/link/email", middleware.Authorized(userHandler.UnlinkEmail)).Methods(http.MethodDelete)
+	ur.HandleFunc("/link/phone", middleware.Authorized(userHandler.UnlinkPhoneNumber)).Methods(http.MethodDelete)
+	ur.HandleFunc("", middleware.RequireScopes(userHandler.Update, auth.ScopeProfileWrite)).Methods(http.MethodPatch)
+	ur.HandleFunc("/{userId}/report", middleware.Authorized(reportsHandler.ReportUser)).Methods(http.MethodPost)
 
 	// user friends routes
 	ufr := v1.PathPrefix("/friend").Subrouter()
-	ufr.HandleFunc("", middleware.Authorized(userFriendsHandler.CreateUserFriends)).Methods(http.MethodPost)
-	ufr.HandleFunc("", middleware.Authorized(userFriendsHandler.DeleteUserFriends)).Methods(http.MethodDelete)
+	ufr.HandleFunc("", middleware.RequireScopes(userFriendsHandler.CreateUserFriends, auth.ScopeFriendsWrite)).Methods(http.MethodPost)
+	ufr.HandleFunc("", middleware.RequireScopes(userFriendsHandler.DeleteUserFriends, auth.ScopeFriendsWrite)).Methods(http.MethodDelete)
 	ufr.HandleFunc("", middleware.Authorized(userHandler.ListUser)).Methods(http.MethodGet)
+	ufr.HandleFunc("/request/incoming", middleware.Authorized(userFriendsHandler.ListIncomingFriendRequests)).Methods(http.MethodGet)
+	ufr.HandleFunc("/request/outgoing", middleware.Authorized(userFriendsHandler.ListOutgoingFriendRequests)).Methods(http.MethodGet)
+	ufr.HandleFunc("/request/{requestId}/accept", middleware.RequireScopes(userFriendsHandler.AcceptFriendRequest, auth.ScopeFriendsWrite)).Methods(http.MethodPost)
+	ufr.HandleFunc("/request/{requestId}/reject", middleware.RequireScopes(userFriendsHandler.RejectFriendRequest, auth.ScopeFriendsWrite)).Methods(http.MethodPost)
+	ufr.HandleFunc("/request/{requestId}/cancel", middleware.RequireScopes(userFriendsHandler.CancelFriendRequest, auth.ScopeFriendsWrite)).Methods(http.MethodPost)
 
 	// image routes
 	ir := v1.PathPrefix("/image").Subrouter()
-	ir.HandleFunc("", middleware.Authorized(imageHandler.UploadToS3)).Methods(http.MethodPost)
+	ir.HandleFunc("", middleware.RequireScopes(imageHandler.Upload, auth.ScopeImagesWrite)).Methods(http.MethodPost)
+	ir.HandleFunc("/upload-url", middleware.RequireScopes(imageHandler.CreateUploadURL, auth.ScopeImagesWrite)).Methods(http.MethodPost)
+	ir.HandleFunc("/{imageId}/confirm", middleware.RequireScopes(imageHandler.Confirm, auth.ScopeImagesWrite)).Methods(http.MethodPost)
+	ir.HandleFunc("/{imageId}", middleware.RequireScopes(imageHandler.Delete, auth.ScopeImagesWrite)).Methods(http.MethodDelete)
 
 	// posts routes
 	pr := v1.PathPrefix("/post").Subrouter()
-	pr.HandleFunc("", middleware.Authorized(postsHandler.CreatePost)).Methods(http.MethodPost)
-	pr.HandleFunc("/comment", middleware.Authorized(postsHandler.CreatePostComment)).Methods(http.MethodPost)
+	pr.HandleFunc("", middleware.RequireScopes(postsHandler.CreatePost, auth.ScopePostsWrite)).Methods(http.MethodPost)
+	pr.HandleFunc("/comment", middleware.RequireScopes(commentsHandler.CreateComment, auth.ScopePostsWrite)).Methods(http.MethodPost)
+	pr.HandleFunc("/comment/{commentId}", middleware.RequireScopes(commentsHandler.UpdateComment, auth.ScopePostsWrite)).Methods(http.MethodPatch)
+	pr.HandleFunc("/comment/{commentId}", middleware.RequireScopes(commentsHandler.DeleteComment, auth.ScopePostsWrite)).Methods(http.MethodDelete)
+	pr.HandleFunc("/comment/{commentId}/replies", middleware.Authorized(commentsHandler.ListReplies)).Methods(http.MethodGet)
+	pr.HandleFunc("/comment/{commentId}/reaction", middleware.RequireScopes(postsHandler.ReactToComment, auth.ScopePostsWrite)).Methods(http.MethodPost)
+	pr.HandleFunc("/comment/{commentId}/reaction", middleware.Authorized(postsHandler.ListCommentReactions)).Methods(http.MethodGet)
+	pr.HandleFunc("/comment/{commentId}/report", middleware.Authorized(reportsHandler.ReportComment)).Methods(http.MethodPost)
 	pr.HandleFunc("", middleware.Authorized(postsHandler.ListPost)).Methods(http.MethodGet)
+	pr.HandleFunc("/{postId}", middleware.Authorized(postsHandler.GetPost)).Methods(http.MethodGet)
+	pr.HandleFunc("/{postId}", middleware.RequireScopes(postsHandler.UpdatePost, auth.ScopePostsWrite)).Methods(http.MethodPatch)
+	pr.HandleFunc("/{postId}", middleware.RequireScopes(postsHandler.DeletePost, auth.ScopePostsWrite)).Methods(http.MethodDelete)
+	pr.HandleFunc("/{postId}/revisions", middleware.Authorized(postsHandler.ListPostRevisions)).Methods(http.MethodGet)
+	pr.HandleFunc("/{postId}/comments", middleware.Authorized(commentsHandler.ListPostComments)).Methods(http.MethodGet)
+	pr.HandleFunc("/{postId}/reaction", middleware.RequireScopes(postsHandler.ReactToPost, auth.ScopePostsWrite)).Methods(http.MethodPost)
+	pr.HandleFunc("/{postId}/reaction", middleware.Authorized(postsHandler.ListPostReactions)).Methods(http.MethodGet)
+	pr.HandleFunc("/{postId}/report", middleware.Authorized(reportsHandler.ReportPost)).Methods(http.MethodPost)
+
+	// admin routes
+	ar := v1.PathPrefix("/admin").Subrouter()
+	ar.HandleFunc("/users", middleware.RequireScopes(adminHandler.SearchUsers, auth.ScopeAdmin)).Methods(http.MethodGet)
+	ar.HandleFunc("/users/{userId}/suspend", middleware.RequireScopes(adminHandler.SuspendUser, auth.ScopeAdmin)).Methods(http.MethodPost)
+	ar.HandleFunc("/users/{userId}/ban", middleware.RequireScopes(adminHandler.BanUser, auth.ScopeAdmin)).Methods(http.MethodPost)
+	ar.HandleFunc("/users/{userId}/unban", middleware.RequireScopes(adminHandler.UnbanUser, auth.ScopeAdmin)).Methods(http.MethodPost)
+	ar.HandleFunc("/users/{userId}/password-reset", middleware.RequireScopes(adminHandler.ForcePasswordReset, auth.ScopeAdmin)).Methods(http.MethodPost)
+	ar.HandleFunc("/users/{userId}/friends", middleware.RequireScopes(adminHandler.ListFriends, auth.ScopeAdmin)).Methods(http.MethodGet)
+	ar.HandleFunc("/users/{userId}/posts", middleware.RequireScopes(adminHandler.ListPosts, auth.ScopeAdmin)).Methods(http.MethodGet)
+	ar.HandleFunc("/reports", middleware.RequireScopes(adminHandler.ListReports, auth.ScopeAdmin)).Methods(http.MethodGet)
+	ar.HandleFunc("/reports/{caseId}", middleware.RequireScopes(adminHandler.GetReport, auth.ScopeAdmin)).Methods(http.MethodGet)
+	ar.HandleFunc("/reports/{caseId}/dismiss", middleware.RequireScopes(adminHandler.DismissReport, auth.ScopeAdmin)).Methods(http.MethodPost)
+	ar.HandleFunc("/reports/{caseId}/take-down", middleware.RequireScopes(adminHandler.TakeDownReport, auth.ScopeAdmin)).Methods(http.MethodPost)
+
+	sweepInterval, err := time.ParseDuration(getEnv("IMAGE_SWEEP_INTERVAL", "1h"))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Invalid IMAGE_SWEEP_INTERVAL: %v", err))
+		os.Exit(1)
+	}
+	orphanGrace, err := time.ParseDuration(getEnv("IMAGE_ORPHAN_GRACE", "24h"))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Invalid IMAGE_ORPHAN_GRACE: %v", err))
+		os.Exit(1)
+	}
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	defer stopSweeper()
+	image.StartSweeper(sweeperCtx, imageService, sweepInterval, orphanGrace)
 
 	httpServer := &http.Server{
 		Addr:     ":8080",
@@ -144,7 +361,14 @@ func main() {
 
 	slog.Info(fmt.Sprintf("Shutting down HTTP server listening on %s", httpServer.Addr))
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
-		slog.Error("HTTP server shutdown error: %v", err)
+		slog.Error(fmt.Sprintf("HTTP server shutdown error: %v", err))
 	}
 	slog.Info("Shutdown complete.")
 }
+
+func getEnv(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
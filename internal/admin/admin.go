@@ -0,0 +1,30 @@
+package admin
+
+import "time"
+
+type Action string
+
+var (
+	ActionSearchUsers        Action = "search_users"
+	ActionSuspendUser        Action = "suspend_user"
+	ActionBanUser            Action = "ban_user"
+	ActionUnbanUser          Action = "unban_user"
+	ActionForcePasswordReset Action = "force_password_reset"
+	ActionViewFriends        Action = "view_friends"
+	ActionViewPosts          Action = "view_posts"
+	ActionListReports        Action = "list_reports"
+	ActionViewReport         Action = "view_report"
+	ActionDismissReport      Action = "dismiss_report"
+	ActionTakeDownReport     Action = "take_down_report"
+)
+
+// AuditEntry records an action of an admin. Entries are append-only, the database refuses to change them.
+type AuditEntry struct {
+	ID           string
+	AdminID      string
+	Action       Action
+	TargetUserID *string
+	Reason       string
+	IP           string
+	CreatedAt    time.Time
+}
@@ -0,0 +1,8 @@
+package admin
+
+import "errors"
+
+var (
+	ErrValidationFailed = errors.New("validation failed")
+	ErrTargetIsAdmin    = errors.New("admins cannot be moderated")
+)
@@ -0,0 +1,353 @@
+package admin
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/request"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/citadel-corp/segokuning-social-app/internal/reports"
+	"github.com/citadel-corp/segokuning-social-app/internal/user"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	adminID, err := getAdminID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var req user.SearchUserPayload
+
+	newSchema := schema.NewDecoder()
+	newSchema.IgnoreUnknownKeys(true)
+	if err := newSchema.Decode(&req, r.URL.Query()); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var params = r.URL.Query()
+	if v, ok := request.CheckPositiveInt(params, "limit"); ok {
+		req.Limit = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if v, ok := request.CheckPositiveInt(params, "offset"); ok {
+		req.Offset = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	usersResp, pagination, err := h.service.SearchUsers(r.Context(), adminID, req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Users fetched successfully",
+		Data:    usersResp,
+		Meta:    pagination,
+	})
+}
+
+func (h *Handler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, user.StatusSuspended, "User suspended successfully")
+}
+
+func (h *Handler) BanUser(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, user.StatusBanned, "User banned successfully")
+}
+
+func (h *Handler) UnbanUser(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, user.StatusActive, "User reinstated successfully")
+}
+
+func (h *Handler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	h.moderate(w, r, "userId", "Password reset required successfully", func(adminID, userID string, req ModerationPayload) error {
+		return h.service.ForcePasswordReset(r.Context(), adminID, userID, req)
+	})
+}
+
+func (h *Handler) ListFriends(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, "Friends fetched successfully", func(adminID, userID string, req ListPayload) (any, *response.Pagination, error) {
+		return h.service.ListFriends(r.Context(), adminID, userID, req)
+	})
+}
+
+func (h *Handler) ListPosts(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, "Posts fetched successfully", func(adminID, userID string, req ListPayload) (any, *response.Pagination, error) {
+		return h.service.ListPosts(r.Context(), adminID, userID, req)
+	})
+}
+
+func (h *Handler) ListReports(w http.ResponseWriter, r *http.Request) {
+	adminID, err := getAdminID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var req reports.ListCasePayload
+
+	newSchema := schema.NewDecoder()
+	newSchema.IgnoreUnknownKeys(true)
+	if err := newSchema.Decode(&req, r.URL.Query()); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var params = r.URL.Query()
+	if v, ok := request.CheckPositiveInt(params, "limit"); ok {
+		req.Limit = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if v, ok := request.CheckPositiveInt(params, "offset"); ok {
+		req.Offset = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	casesResp, pagination, err := h.service.ListReports(r.Context(), adminID, req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Reports fetched successfully",
+		Data:    casesResp,
+		Meta:    pagination,
+	})
+}
+
+func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
+	adminID, err := getAdminID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	caseResp, err := h.service.GetReport(r.Context(), adminID, mux.Vars(r)["caseId"])
+	if errors.Is(err, reports.ErrCaseNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Report fetched successfully",
+		Data:    caseResp,
+	})
+}
+
+func (h *Handler) DismissReport(w http.ResponseWriter, r *http.Request) {
+	h.decideReport(w, r, reports.StatusDismissed, "Report dismissed successfully")
+}
+
+func (h *Handler) TakeDownReport(w http.ResponseWriter, r *http.Request) {
+	h.decideReport(w, r, reports.StatusTakenDown, "Reported content taken down successfully")
+}
+
+func (h *Handler) setStatus(w http.ResponseWriter, r *http.Request, status user.Status, message string) {
+	h.moderate(w, r, "userId", message, func(adminID, userID string, req ModerationPayload) error {
+		return h.service.SetStatus(r.Context(), adminID, userID, status, req)
+	})
+}
+
+func (h *Handler) decideReport(w http.ResponseWriter, r *http.Request, status reports.Status, message string) {
+	h.moderate(w, r, "caseId", message, func(adminID, caseID string, req ModerationPayload) error {
+		return h.service.DecideReport(r.Context(), adminID, caseID, status, req)
+	})
+}
+
+// moderate runs an action on the user or report named by the idVar route variable.
+func (h *Handler) moderate(w http.ResponseWriter, r *http.Request, idVar string, message string,
+	act func(adminID, targetID string, req ModerationPayload) error) {
+	adminID, err := getAdminID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var req ModerationPayload
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	err = act(adminID, mux.Vars(r)[idVar], req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, user.ErrUserNotFound) || errors.Is(err, reports.ErrCaseNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, reports.ErrCaseAlreadyDecided) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrTargetIsAdmin) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: message,
+	})
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, message string,
+	fetch func(adminID, userID string, req ListPayload) (any, *response.Pagination, error)) {
+	adminID, err := getAdminID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var req ListPayload
+
+	var params = r.URL.Query()
+	if v, ok := request.CheckPositiveInt(params, "limit"); ok {
+		req.Limit = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if v, ok := request.CheckPositiveInt(params, "offset"); ok {
+		req.Offset = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	data, pagination, err := fetch(adminID, mux.Vars(r)["userId"], req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, user.ErrUserNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: message,
+		Data:    data,
+		Meta:    pagination,
+	})
+}
+
+func getAdminID(r *http.Request) (string, error) {
+	if principal, ok := middleware.PrincipalFromContext(r.Context()); ok {
+		return principal.UserID, nil
+	}
+	slog.Error("cannot parse auth value from context")
+	return "", errors.New("cannot parse auth value from context")
+}
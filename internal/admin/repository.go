@@ -0,0 +1,31 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+)
+
+type Repository interface {
+	CreateAuditEntry(ctx context.Context, entry *AuditEntry) error
+}
+
+type dbRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+// CreateAuditEntry implements Repository.
+func (d *dbRepository) CreateAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+			INSERT INTO admin_audit_logs (
+				id, admin_id, action, target_user_id, reason, ip
+			) VALUES (
+				$1, $2, $3, $4, $5, $6
+			)
+		`, entry.ID, entry.AdminID, entry.Action, entry.TargetUserID, entry.Reason, entry.IP)
+	return err
+}
@@ -0,0 +1,27 @@
+package admin
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type ModerationPayload struct {
+	Reason string `json:"reason"`
+}
+
+func (p ModerationPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Reason, validation.Required, validation.Length(1, 500)),
+	)
+}
+
+type ListPayload struct {
+	Limit  int `schema:"limit" binding:"omitempty"`
+	Offset int `schema:"offset" binding:"omitempty"`
+}
+
+func (p ListPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Limit, validation.Min(0), validation.Max(100)),
+		validation.Field(&p.Offset, validation.Min(0)),
+	)
+}
@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/posts"
+	"github.com/citadel-corp/segokuning-social-app/internal/user"
+)
+
+type UserResponse struct {
+	ID                    string      `json:"userId"`
+	Name                  string      `json:"name"`
+	Email                 *string     `json:"email"`
+	EmailVerified         bool        `json:"emailVerified"`
+	Phone                 *string     `json:"phone"`
+	PhoneVerified         bool        `json:"phoneVerified"`
+	ImageURL              *string     `json:"imageUrl"`
+	FriendCount           int         `json:"friendCount"`
+	Role                  string      `json:"role"`
+	Status                user.Status `json:"status"`
+	PasswordResetRequired bool        `json:"passwordResetRequired"`
+	CreatedAt             time.Time   `json:"createdAt"`
+}
+
+type UserPostResponse struct {
+	PostID string             `json:"postId"`
+	Post   posts.PostResponse `json:"post"`
+}
@@ -0,0 +1,308 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/auth"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/id"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/citadel-corp/segokuning-social-app/internal/posts"
+	"github.com/citadel-corp/segokuning-social-app/internal/reports"
+	"github.com/citadel-corp/segokuning-social-app/internal/session"
+	"github.com/citadel-corp/segokuning-social-app/internal/user"
+)
+
+type Service interface {
+	SearchUsers(ctx context.Context, adminID string, req user.SearchUserPayload) ([]UserResponse, *response.Pagination, error)
+	SetStatus(ctx context.Context, adminID string, userID string, status user.Status, req ModerationPayload) error
+	ForcePasswordReset(ctx context.Context, adminID string, userID string, req ModerationPayload) error
+	ListFriends(ctx context.Context, adminID string, userID string, req ListPayload) ([]user.UserListResponse, *response.Pagination, error)
+	ListPosts(ctx context.Context, adminID string, userID string, req ListPayload) ([]UserPostResponse, *response.Pagination, error)
+	ListReports(ctx context.Context, adminID string, req reports.ListCasePayload) ([]reports.CaseResponse, *response.Pagination, error)
+	GetReport(ctx context.Context, adminID string, caseID string) (*reports.CaseDetailResponse, error)
+	DecideReport(ctx context.Context, adminID string, caseID string, status reports.Status, req ModerationPayload) error
+}
+
+type adminService struct {
+	repository        Repository
+	userRepository    user.Repository
+	postsRepository   posts.Repository
+	reportsRepository reports.Repository
+	sessionService    session.Service
+}
+
+func NewService(repository Repository, userRepository user.Repository, postsRepository posts.Repository,
+	reportsRepository reports.Repository, sessionService session.Service) Service {
+	return &adminService{
+		repository:        repository,
+		userRepository:    userRepository,
+		postsRepository:   postsRepository,
+		reportsRepository: reportsRepository,
+		sessionService:    sessionService,
+	}
+}
+
+var statusActions = map[user.Status]Action{
+	user.StatusSuspended: ActionSuspendUser,
+	user.StatusBanned:    ActionBanUser,
+	user.StatusActive:    ActionUnbanUser,
+}
+
+// SearchUsers implements Service.
+func (s *adminService) SearchUsers(ctx context.Context, adminID string, req user.SearchUserPayload) ([]UserResponse, *response.Pagination, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	err = s.record(ctx, adminID, ActionSearchUsers, nil, fmt.Sprintf("email=%q phone=%q", req.Email, req.Phone))
+	if err != nil {
+		return nil, nil, err
+	}
+	users, pagination, err := s.userRepository.Search(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp := make([]UserResponse, len(users))
+	for i, u := range users {
+		resp[i] = UserResponse{
+			ID:                    u.ID,
+			Name:                  u.Name,
+			Email:                 u.Email,
+			EmailVerified:         u.EmailVerifiedAt != nil,
+			Phone:                 u.PhoneNumber,
+			PhoneVerified:         u.PhoneNumberVerifiedAt != nil,
+			ImageURL:              u.ImageURL,
+			FriendCount:           u.FriendCount,
+			Role:                  u.Role,
+			Status:                u.Status,
+			PasswordResetRequired: u.PasswordResetRequired,
+			CreatedAt:             u.CreatedAt,
+		}
+	}
+	return resp, pagination, nil
+}
+
+// SetStatus suspends, bans or reinstates the user. Suspended and banned users are logged out,
+// and their posts are hidden until they are reinstated.
+func (s *adminService) SetStatus(ctx context.Context, adminID string, userID string, status user.Status, req ModerationPayload) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	_, err = s.getModeratedUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = s.record(ctx, adminID, statusActions[status], &userID, req.Reason)
+	if err != nil {
+		return err
+	}
+	err = s.userRepository.UpdateStatus(ctx, userID, status)
+	if err != nil {
+		return err
+	}
+	if status == user.StatusActive {
+		return nil
+	}
+	return s.sessionService.RevokeAll(ctx, userID)
+}
+
+// ForcePasswordReset logs the user out and blocks their logins until they reset their password
+// with a code from the forgot password endpoint.
+func (s *adminService) ForcePasswordReset(ctx context.Context, adminID string, userID string, req ModerationPayload) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	_, err = s.getModeratedUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = s.record(ctx, adminID, ActionForcePasswordReset, &userID, req.Reason)
+	if err != nil {
+		return err
+	}
+	err = s.userRepository.RequirePasswordReset(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.sessionService.RevokeAll(ctx, userID)
+}
+
+// ListFriends implements Service.
+func (s *adminService) ListFriends(ctx context.Context, adminID string, userID string, req ListPayload) ([]user.UserListResponse, *response.Pagination, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	_, err = s.userRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = s.record(ctx, adminID, ActionViewFriends, &userID, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	friends, pagination, err := s.userRepository.List(ctx, user.ListUserPayload{
+		OnlyFriend: true,
+		UserID:     userID,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if friends == nil {
+		friends = []user.UserListResponse{}
+	}
+	return friends, pagination, nil
+}
+
+// ListPosts returns every post of the user, including the ones hidden by moderation.
+func (s *adminService) ListPosts(ctx context.Context, adminID string, userID string, req ListPayload) ([]UserPostResponse, *response.Pagination, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	_, err = s.userRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = s.record(ctx, adminID, ActionViewPosts, &userID, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	userPosts, pagination, err := s.postsRepository.ListByUser(ctx, userID, req.Limit, req.Offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp := make([]UserPostResponse, len(userPosts))
+	for i, p := range userPosts {
+		resp[i] = UserPostResponse{PostID: p.ID, Post: p}
+	}
+	return resp, pagination, nil
+}
+
+// ListReports returns the moderation queue, the open cases by default.
+func (s *adminService) ListReports(ctx context.Context, adminID string, req reports.ListCasePayload) ([]reports.CaseResponse, *response.Pagination, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	err = s.record(ctx, adminID, ActionListReports, nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.reportsRepository.ListCases(ctx, req)
+}
+
+// GetReport returns the case along with each of its reports.
+func (s *adminService) GetReport(ctx context.Context, adminID string, caseID string) (*reports.CaseDetailResponse, error) {
+	c, err := s.reportsRepository.GetCase(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.record(ctx, adminID, ActionViewReport, &c.TargetUserID, "")
+	if err != nil {
+		return nil, err
+	}
+	caseReports, err := s.reportsRepository.ListReports(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	reasons := map[reports.Reason]int{}
+	for _, r := range caseReports {
+		reasons[r.Reason]++
+	}
+	return &reports.CaseDetailResponse{
+		CaseResponse: reports.CaseResponse{
+			ID:           c.ID,
+			TargetType:   c.TargetType,
+			TargetID:     c.TargetID,
+			TargetUserID: c.TargetUserID,
+			Status:       c.Status,
+			ReportCount:  c.ReportCount,
+			Reasons:      reasons,
+			Hidden:       c.HiddenAt != nil,
+			DecidedBy:    c.DecidedBy,
+			DecisionNote: c.DecisionNote,
+			DecidedAt:    c.DecidedAt,
+			CreatedAt:    c.CreatedAt,
+		},
+		Reports: caseReports,
+	}, nil
+}
+
+// DecideReport dismisses or takes down an open case, with the reason recorded as its decision note.
+// Taking down a post or comment keeps it hidden, taking down a user suspends them.
+func (s *adminService) DecideReport(ctx context.Context, adminID string, caseID string, status reports.Status, req ModerationPayload) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	c, err := s.reportsRepository.GetCase(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if c.Status != reports.StatusOpen {
+		return reports.ErrCaseAlreadyDecided
+	}
+	suspendUser := c.TargetType == reports.TargetUser && status == reports.StatusTakenDown
+	if suspendUser {
+		_, err = s.getModeratedUser(ctx, c.TargetUserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	action := ActionDismissReport
+	if status == reports.StatusTakenDown {
+		action = ActionTakeDownReport
+	}
+	err = s.record(ctx, adminID, action, &c.TargetUserID, req.Reason)
+	if err != nil {
+		return err
+	}
+
+	c.Status = status
+	c.DecidedBy = &adminID
+	c.DecisionNote = req.Reason
+	err = s.reportsRepository.Decide(ctx, c)
+	if err != nil {
+		return err
+	}
+	if !suspendUser {
+		return nil
+	}
+	err = s.userRepository.UpdateStatus(ctx, c.TargetUserID, user.StatusSuspended)
+	if err != nil {
+		return err
+	}
+	return s.sessionService.RevokeAll(ctx, c.TargetUserID)
+}
+
+// getModeratedUser returns the user an admin is about to act on, admins cannot act on each other.
+func (s *adminService) getModeratedUser(ctx context.Context, userID string) (*user.User, error) {
+	u, err := s.userRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Role == auth.RoleAdmin {
+		return nil, ErrTargetIsAdmin
+	}
+	return u, nil
+}
+
+// record writes the action to the audit log before it is carried out, so that no action goes unrecorded.
+func (s *adminService) record(ctx context.Context, adminID string, action Action, targetUserID *string, reason string) error {
+	return s.repository.CreateAuditEntry(ctx, &AuditEntry{
+		ID:           id.GenerateStringID(16),
+		AdminID:      adminID,
+		Action:       action,
+		TargetUserID: targetUserID,
+		Reason:       reason,
+		IP:           middleware.ClientFromContext(ctx).IP,
+	})
+}
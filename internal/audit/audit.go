@@ -0,0 +1,30 @@
+package audit
+
+import "time"
+
+type Action string
+
+var (
+	ActionPasswordChanged     Action = "password_changed"
+	ActionPasswordReset       Action = "password_reset"
+	ActionEmailLinked         Action = "email_linked"
+	ActionEmailReplaced       Action = "email_replaced"
+	ActionEmailUnlinked       Action = "email_unlinked"
+	ActionPhoneNumberLinked   Action = "phone_number_linked"
+	ActionPhoneNumberReplaced Action = "phone_number_replaced"
+	ActionPhoneNumberUnlinked Action = "phone_number_unlinked"
+	ActionTwoFactorEnabled    Action = "two_factor_enabled"
+	ActionTwoFactorDisabled   Action = "two_factor_disabled"
+	ActionRecoveryCodeUsed    Action = "recovery_code_used"
+)
+
+// Event is a security relevant change made to a user account. Events are never updated or deleted.
+type Event struct {
+	ID        string
+	UserID    string
+	Action    Action
+	Detail    string
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+}
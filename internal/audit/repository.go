@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+)
+
+type Repository interface {
+	Create(ctx context.Context, event *Event) error
+}
+
+type dbRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+// Create implements Repository.
+func (d *dbRepository) Create(ctx context.Context, event *Event) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+			INSERT INTO security_audit_logs (
+				id, user_id, action, detail, ip, user_agent
+			) VALUES (
+				$1, $2, $3, $4, $5, $6
+			)
+		`, event.ID, event.UserID, event.Action, event.Detail, event.IP, event.UserAgent)
+	return err
+}
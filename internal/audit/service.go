@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/id"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
+)
+
+type Service interface {
+	Record(ctx context.Context, userID string, action Action, detail string) error
+}
+
+type auditService struct {
+	repository Repository
+}
+
+func NewService(repository Repository) Service {
+	return &auditService{repository: repository}
+}
+
+// Record appends an event to the security audit log of the user, along with the client of the current request.
+func (s *auditService) Record(ctx context.Context, userID string, action Action, detail string) error {
+	client := middleware.ClientFromContext(ctx)
+	return s.repository.Create(ctx, &Event{
+		ID:        id.GenerateStringID(16),
+		UserID:    userID,
+		Action:    action,
+		Detail:    detail,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	})
+}
@@ -0,0 +1,14 @@
+package comments
+
+import "time"
+
+type Comment struct {
+	ID         uint64
+	UserID     string
+	PostID     string
+	PostUserID string
+	ParentID   *uint64
+	Content    string
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+}
@@ -0,0 +1,228 @@
+package comments
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/request"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	var req CreateCommentPayload
+	var resp Response
+	var err error
+
+	userID, err := getUserID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	req.UserID = userID
+
+	err = req.Validate()
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	resp = h.service.Create(r.Context(), req)
+	response.JSON(w, resp.Code, response.ResponseBody{
+		Message: resp.Message,
+		Error:   resp.Error,
+	})
+}
+
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	var req UpdateCommentPayload
+	var resp Response
+	var err error
+
+	userID, err := getUserID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := getCommentID(r)
+	if err != nil {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: ErrorNotFound.Message,
+		})
+		return
+	}
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	req.UserID = userID
+	req.CommentID = commentID
+
+	err = req.Validate()
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	resp = h.service.Update(r.Context(), req)
+	response.JSON(w, resp.Code, response.ResponseBody{
+		Message: resp.Message,
+		Error:   resp.Error,
+	})
+}
+
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := getCommentID(r)
+	if err != nil {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: ErrorNotFound.Message,
+		})
+		return
+	}
+
+	req := DeleteCommentPayload{
+		UserID:    userID,
+		CommentID: commentID,
+	}
+
+	err = req.Validate()
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	resp := h.service.Delete(r.Context(), req)
+	response.JSON(w, resp.Code, response.ResponseBody{
+		Message: resp.Message,
+		Error:   resp.Error,
+	})
+}
+
+func (h *Handler) ListPostComments(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, func(req *ListCommentPayload) error {
+		req.PostID = mux.Vars(r)["postId"]
+		return nil
+	})
+}
+
+func (h *Handler) ListReplies(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, func(req *ListCommentPayload) error {
+		commentID, err := getCommentID(r)
+		req.ParentID = commentID
+		return err
+	})
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, setTarget func(req *ListCommentPayload) error) {
+	userID, err := getUserID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	req := ListCommentPayload{
+		UserID: userID,
+	}
+	if err := setTarget(&req); err != nil {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: ErrorNotFound.Message,
+		})
+		return
+	}
+
+	var params = r.URL.Query()
+	if v, ok := request.CheckPositiveInt(params, "limit"); ok {
+		req.Limit = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if v, ok := request.CheckPositiveInt(params, "offset"); ok {
+		req.Offset = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if v, ok := request.CheckEnum(params, "orderBy", []string{"asc", "desc"}); ok {
+		req.OrderBy = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = req.Validate()
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	resp := h.service.List(r.Context(), req)
+	response.JSON(w, resp.Code, response.ResponseBody{
+		Message: resp.Message,
+		Data:    resp.Data,
+		Meta:    resp.Meta,
+		Error:   resp.Error,
+	})
+}
+
+func getCommentID(r *http.Request) (uint64, error) {
+	return strconv.ParseUint(mux.Vars(r)["commentId"], 10, 64)
+}
+
+func getUserID(r *http.Request) (string, error) {
+	if principal, ok := middleware.PrincipalFromContext(r.Context()); ok {
+		return principal.UserID, nil
+	}
+
+	return "", errors.New("unauthorized")
+}
@@ -0,0 +1,161 @@
+package comments
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+)
+
+type Repository interface {
+	Create(ctx context.Context, comment *Comment) error
+	GetByID(ctx context.Context, id uint64) (*Comment, error)
+	Update(ctx context.Context, comment *Comment) error
+	Delete(ctx context.Context, id uint64) error
+	List(ctx context.Context, filter ListCommentPayload) ([]CommentResponse, *response.Pagination, error)
+	GetPostAuthorID(ctx context.Context, postID string) (string, error)
+}
+
+type dbRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+// Create implements Repository.
+func (d *dbRepository) Create(ctx context.Context, comment *Comment) error {
+	row := d.db.DB().QueryRowContext(ctx, `
+			INSERT INTO comments (
+				user_id, post_id, parent_id, content
+			) VALUES (
+				$1, $2, $3, $4
+			)
+			RETURNING id, created_at
+		`, comment.UserID, comment.PostID, comment.ParentID, comment.Content)
+	return row.Scan(&comment.ID, &comment.CreatedAt)
+}
+
+// GetByID returns the comment along with the author of the post it belongs to. Comments hidden by reports,
+// and comments of posts that GetPostAuthorID does not find, are not found.
+func (d *dbRepository) GetByID(ctx context.Context, id uint64) (*Comment, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT c.id, c.user_id, c.post_id, p.user_id, c.parent_id, c.content, c.created_at, c.updated_at
+		FROM comments c
+		JOIN posts p ON p.id = c.post_id AND p.hidden_at IS NULL
+		JOIN users pu ON pu.id = p.user_id AND pu.status = 'active'
+		WHERE c.id = $1 AND c.hidden_at IS NULL;
+	`, id)
+
+	c := &Comment{}
+	err := row.Scan(&c.ID, &c.UserID, &c.PostID, &c.PostUserID, &c.ParentID, &c.Content, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update implements Repository.
+func (d *dbRepository) Update(ctx context.Context, comment *Comment) error {
+	row := d.db.DB().QueryRowContext(ctx, `
+		UPDATE comments
+		SET content = $1,
+		updated_at = current_timestamp
+		WHERE id = $2
+		RETURNING updated_at;
+	`, comment.Content, comment.ID)
+	return row.Scan(&comment.UpdatedAt)
+}
+
+// Delete removes the comment, its replies are removed along with it.
+func (d *dbRepository) Delete(ctx context.Context, id uint64) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		DELETE FROM comments
+		WHERE id = $1;
+	`, id)
+	return err
+}
+
+// List returns the top-level comments of a post, or the replies of a comment when ParentID is set.
+// Comments hidden by reports are left out.
+func (d *dbRepository) List(ctx context.Context, filter ListCommentPayload) ([]CommentResponse, *response.Pagination, error) {
+	var orderBy string
+	switch filter.OrderBy {
+	case "asc":
+		orderBy = "asc"
+	default:
+		orderBy = "desc"
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = 5
+	}
+
+	pagination := &response.Pagination{
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+
+	var (
+		whereStatement string
+		arg            interface{}
+	)
+	if filter.ParentID != 0 {
+		whereStatement = "WHERE c.parent_id = $1 AND c.hidden_at IS NULL"
+		arg = filter.ParentID
+	} else {
+		whereStatement = "WHERE c.post_id = $1 AND c.parent_id IS NULL AND c.hidden_at IS NULL"
+		arg = filter.PostID
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER() AS total_count, c.id, c.parent_id, c."content" as "comment",
+			c.created_at as comment_created_at, c.updated_at,
+			(SELECT COUNT(*) FROM "comments" r WHERE r.parent_id = c.id AND r.hidden_at IS NULL) as reply_count,
+			cu.id as userId, cu.name as name, cu.image_url as imageUrl, cu.friend_count as friendCount
+		FROM "comments" c
+		JOIN users cu ON cu.id = c.user_id
+		%s
+		ORDER BY c.created_at %s, c.id %s
+		LIMIT $2 OFFSET $3;
+	`, whereStatement, orderBy, orderBy)
+
+	rows, err := d.db.DB().QueryContext(ctx, query, arg, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	resp := []CommentResponse{}
+	for rows.Next() {
+		var c CommentResponse
+		if err := rows.Scan(&pagination.Total, &c.ID, &c.ParentID, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.ReplyCount,
+			&c.User.ID, &c.User.Name, &c.User.ImageURL, &c.User.FriendCount); err != nil {
+			return resp, nil, err
+		}
+		resp = append(resp, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return resp, nil, err
+	}
+
+	return resp, pagination, nil
+}
+
+// GetPostAuthorID returns the author of the post. Posts hidden by reports, or because their author was
+// suspended or banned, are not found.
+func (d *dbRepository) GetPostAuthorID(ctx context.Context, postID string) (string, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT posts.user_id
+		FROM posts
+		JOIN users ON users.id = posts.user_id AND users.status = 'active'
+		WHERE posts.id = $1 AND posts.hidden_at IS NULL;
+	`, postID)
+
+	var userID string
+	err := row.Scan(&userID)
+	return userID, err
+}
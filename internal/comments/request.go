@@ -0,0 +1,63 @@
+package comments
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type CreateCommentPayload struct {
+	UserID   string
+	PostID   string  `json:"postId"`
+	ParentID *uint64 `json:"parentId"`
+	Comment  string  `json:"comment"`
+}
+
+func (p CreateCommentPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UserID, validation.Required.Error(ErrorUnauthorized.Message)),
+		validation.Field(&p.PostID, validation.Required),
+		validation.Field(&p.ParentID, validation.NilOrNotEmpty),
+		validation.Field(&p.Comment, validation.Required, validation.Length(2, 500)),
+	)
+}
+
+type UpdateCommentPayload struct {
+	UserID    string
+	CommentID uint64
+	Comment   string `json:"comment"`
+}
+
+func (p UpdateCommentPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UserID, validation.Required.Error(ErrorUnauthorized.Message)),
+		validation.Field(&p.CommentID, validation.Required),
+		validation.Field(&p.Comment, validation.Required, validation.Length(2, 500)),
+	)
+}
+
+type DeleteCommentPayload struct {
+	UserID    string
+	CommentID uint64
+}
+
+func (p DeleteCommentPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UserID, validation.Required.Error(ErrorUnauthorized.Message)),
+		validation.Field(&p.CommentID, validation.Required),
+	)
+}
+
+type ListCommentPayload struct {
+	UserID   string
+	PostID   string
+	ParentID uint64
+	Limit    int
+	Offset   int
+	OrderBy  string
+}
+
+func (p ListCommentPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UserID, validation.Required.Error(ErrorUnauthorized.Message)),
+		validation.Field(&p.PostID, validation.When(p.ParentID == 0, validation.Required)),
+	)
+}
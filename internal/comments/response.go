@@ -3,12 +3,33 @@ package comments
 import (
 	"time"
 
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/citadel-corp/segokuning-social-app/internal/reactions"
 	"github.com/citadel-corp/segokuning-social-app/internal/user"
 )
 
+type Response struct {
+	Code    int
+	Message string
+	Data    any
+	Meta    *response.Pagination
+	Error   string
+}
+
+var (
+	SuccessCreateResponse = Response{Code: 200, Message: "Comment created successfully"}
+	SuccessUpdateResponse = Response{Code: 200, Message: "Comment updated successfully"}
+	SuccessDeleteResponse = Response{Code: 200, Message: "Comment deleted successfully"}
+	SuccessListResponse   = Response{Code: 200, Message: "Comments fetched successfully"}
+)
+
 type CommentResponse struct {
-	ID        *string                  `json:"-"`
-	Content   *string                  `json:"comment"`
-	User      user.UserCommentResponse `json:"creator"`
-	CreatedAt *time.Time               `json:"createdAt"`
+	ID         *string                  `json:"commentId"`
+	ParentID   *string                  `json:"parentId,omitempty"`
+	Content    *string                  `json:"comment"`
+	User       user.UserCommentResponse `json:"creator"`
+	ReplyCount int                      `json:"replyCount"`
+	CreatedAt  *time.Time               `json:"createdAt"`
+	UpdatedAt  *time.Time               `json:"updatedAt,omitempty"`
+	Reactions  reactions.Summary        `json:"reactions"`
 }
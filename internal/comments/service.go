@@ -0,0 +1,231 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/reactions"
+	userfriends "github.com/citadel-corp/segokuning-social-app/internal/user_friends"
+)
+
+type Service interface {
+	Create(ctx context.Context, req CreateCommentPayload) Response
+	Update(ctx context.Context, req UpdateCommentPayload) Response
+	Delete(ctx context.Context, req DeleteCommentPayload) Response
+	List(ctx context.Context, req ListCommentPayload) Response
+}
+
+type commentsService struct {
+	repository            Repository
+	userFriendsRepository userfriends.Repository
+	reactionsRepository   reactions.Repository
+}
+
+func NewService(repository Repository, userFriendsRepository userfriends.Repository, reactionsRepository reactions.Repository) Service {
+	return &commentsService{
+		repository:            repository,
+		userFriendsRepository: userFriendsRepository,
+		reactionsRepository:   reactionsRepository,
+	}
+}
+
+func (s *commentsService) Create(ctx context.Context, req CreateCommentPayload) Response {
+	var resp Response
+
+	postUserID, err := s.repository.GetPostAuthorID(ctx, req.PostID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrorNotFound
+	}
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	//validate post creator is users friend
+	visible, err := s.isVisible(ctx, req.UserID, postUserID)
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+	if !visible {
+		return ErrorBadRequest
+	}
+
+	// replies are only one level deep and must stay on the same post
+	if req.ParentID != nil {
+		parent, err := s.repository.GetByID(ctx, *req.ParentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrParentNotFound
+		}
+		if err != nil {
+			resp = ErrorInternal
+			resp.Error = err.Error()
+			return resp
+		}
+		if parent.PostID != req.PostID {
+			return ErrParentNotFound
+		}
+		if parent.ParentID != nil {
+			return ErrNestedReply
+		}
+	}
+
+	comment := &Comment{
+		UserID:   req.UserID,
+		PostID:   req.PostID,
+		ParentID: req.ParentID,
+		Content:  req.Comment,
+	}
+
+	err = s.repository.Create(ctx, comment)
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	return SuccessCreateResponse
+}
+
+func (s *commentsService) Update(ctx context.Context, req UpdateCommentPayload) Response {
+	var resp Response
+
+	comment, errResp := s.getComment(ctx, req.CommentID)
+	if errResp != nil {
+		return *errResp
+	}
+	if comment.UserID != req.UserID {
+		return ErrorForbidden
+	}
+
+	comment.Content = req.Comment
+	err := s.repository.Update(ctx, comment)
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	return SuccessUpdateResponse
+}
+
+// Delete lets the comment author, or the author of the commented post, remove a comment.
+func (s *commentsService) Delete(ctx context.Context, req DeleteCommentPayload) Response {
+	var resp Response
+
+	comment, errResp := s.getComment(ctx, req.CommentID)
+	if errResp != nil {
+		return *errResp
+	}
+	if comment.UserID != req.UserID && comment.PostUserID != req.UserID {
+		return ErrorForbidden
+	}
+
+	err := s.repository.Delete(ctx, comment.ID)
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	return SuccessDeleteResponse
+}
+
+func (s *commentsService) List(ctx context.Context, req ListCommentPayload) Response {
+	var resp Response
+
+	postID := req.PostID
+	if req.ParentID != 0 {
+		parent, errResp := s.getComment(ctx, req.ParentID)
+		if errResp != nil {
+			return *errResp
+		}
+		postID = parent.PostID
+	}
+
+	postUserID, err := s.repository.GetPostAuthorID(ctx, postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrorNotFound
+	}
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	visible, err := s.isVisible(ctx, req.UserID, postUserID)
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+	if !visible {
+		return ErrorNotFound
+	}
+
+	postComments, pagination, err := s.repository.List(ctx, ListCommentPayload{
+		PostID:   postID,
+		ParentID: req.ParentID,
+		Limit:    req.Limit,
+		Offset:   req.Offset,
+		OrderBy:  req.OrderBy,
+	})
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	commentIDs := make([]string, 0, len(postComments))
+	for _, c := range postComments {
+		commentIDs = append(commentIDs, *c.ID)
+	}
+	summaries, err := s.reactionsRepository.Summarize(ctx, reactions.TargetComment, commentIDs, req.UserID)
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+	for i := range postComments {
+		postComments[i].Reactions = summaries[*postComments[i].ID]
+	}
+
+	resp = SuccessListResponse
+	resp.Data = postComments
+	resp.Meta = pagination
+
+	return resp
+}
+
+func (s *commentsService) getComment(ctx context.Context, id uint64) (*Comment, *Response) {
+	comment, err := s.repository.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &ErrorNotFound
+	}
+	if err != nil {
+		resp := ErrorInternal
+		resp.Error = err.Error()
+		return nil, &resp
+	}
+	return comment, nil
+}
+
+// isVisible reports whether the post author is the user or one of their friends.
+func (s *commentsService) isVisible(ctx context.Context, userID string, postUserID string) (bool, error) {
+	if postUserID == userID {
+		return true, nil
+	}
+
+	_, err := s.userFriendsRepository.GetByFriendID(ctx, userID, postUserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
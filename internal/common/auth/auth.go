@@ -0,0 +1,35 @@
+// Package auth defines the roles users can have and the scopes they grant in access tokens.
+package auth
+
+import "slices"
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+const (
+	ScopePostsWrite   = "posts:write"
+	ScopeFriendsWrite = "friends:write"
+	ScopeImagesWrite  = "images:write"
+	ScopeProfileWrite = "profile:write"
+	ScopeAdmin        = "admin"
+)
+
+var roleScopes = map[string][]string{
+	RoleUser:  {ScopePostsWrite, ScopeFriendsWrite, ScopeImagesWrite, ScopeProfileWrite},
+	RoleAdmin: {ScopePostsWrite, ScopeFriendsWrite, ScopeImagesWrite, ScopeProfileWrite, ScopeAdmin},
+}
+
+// ScopesFor returns the scopes granted by the roles, unknown roles grant none.
+func ScopesFor(roles []string) []string {
+	scopes := []string{}
+	for _, role := range roles {
+		for _, scope := range roleScopes[role] {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,30 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode turns the keyset position into an opaque cursor string.
+func Encode(position any) (string, error) {
+	b, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Decode reads a cursor created by Encode into position.
+func Decode(cursor string, position any) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, position); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package jwt
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+)
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKeys returns the keys tokens can currently be verified with. Tokens signed with JWT_SECRET
+// cannot be verified by other services and have no key here.
+func PublicKeys() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range keys.verification {
+		jwk := toJWK(key)
+		jwk.Kid = key.ID
+		jwk.Use = "sig"
+		jwk.Alg = key.Method.Alg()
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
+
+// JWKSHandler serves PublicKeys, conventionally under /.well-known/jwks.json.
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	response.JSONWithHeaders(w, http.StatusOK, PublicKeys(), http.Header{
+		"Cache-Control": []string{"public, max-age=300"},
+	})
+}
@@ -3,54 +3,120 @@ package jwt
 import (
 	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 var (
-	key = []byte(os.Getenv("JWT_SECRET"))
-
 	ErrUnknownClaims = errors.New("unknown claims type")
 	ErrTokenInvalid  = errors.New("invalid token")
 )
 
-func Sign(ttl time.Duration, subject string) (string, error) {
+const (
+	// PurposeTwoFactor marks a challenge token that can only be exchanged for an access token with a second factor.
+	PurposeTwoFactor = "2fa"
+	// PurposeVerifyCredential marks a challenge token that can only be used to verify the credential the user logged in with.
+	PurposeVerifyCredential = "verify"
+)
+
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string   `json:"sid,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
+	Purpose   string   `json:"pur,omitempty"`
+}
+
+// Sign signs an access token of the subject for the session, granting the roles and scopes.
+func Sign(ttl time.Duration, subject string, sessionID string, roles []string, scopes []string) (string, error) {
+	return sign(ttl, subject, Claims{SessionID: sessionID, Roles: roles, Scopes: scopes})
+}
+
+// SignChallenge signs a token that proves the subject passed the first login step for the purpose.
+// It is not accepted by Verify.
+func SignChallenge(ttl time.Duration, subject string, purpose string) (string, error) {
+	return sign(ttl, subject, Claims{Purpose: purpose})
+}
+
+func sign(ttl time.Duration, subject string, claims Claims) (string, error) {
 	now := time.Now()
 	expiry := now.Add(ttl)
-	t := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		jwt.RegisteredClaims{
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(expiry),
-			Subject:   subject,
-		},
-	)
-	return t.SignedString(key)
-}
-
-func VerifyAndGetSubject(tokenString string) (string, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiry),
+		Subject:   subject,
+	}
+	signing := keys.signing
+	if signing == nil {
+		t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return t.SignedString(keys.secret)
+	}
+	t := jwt.NewWithClaims(signing.Method, claims)
+	t.Header["kid"] = signing.ID
+	return t.SignedString(signing.Private)
+}
+
+func Verify(tokenString string) (*Claims, error) {
+	claims, err := parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
+// VerifyChallenge verifies a token signed by SignChallenge for the purpose.
+func VerifyChallenge(tokenString string, purpose string) (*Claims, error) {
+	claims, err := parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
 
-		return key, nil
-	})
+func parse(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verificationKey)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Checking token validity
 	if !token.Valid {
-		return "", ErrTokenInvalid
+		return nil, ErrTokenInvalid
 	}
 
-	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok {
-		return claims.Subject, nil
+	if claims, ok := token.Claims.(*Claims); ok {
+		return claims, nil
 	} else {
-		return "", ErrUnknownClaims
+		return nil, ErrUnknownClaims
+	}
+}
+
+// verificationKey picks the key of the token by its kid header, and refuses a token whose
+// algorithm does not match the key. HS256 tokens are refused once asymmetric keys are loaded
+// and their migration window is over.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(keys.secret) == 0 || (keys.signing != nil && !time.Now().Before(keys.secretUntil)) {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return keys.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := keys.verification[kid]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
 	}
+	return key.Public, nil
 }
@@ -0,0 +1,173 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported key, expected an RSA or Ed25519 key in PEM format")
+	ErrUnknownKeyID   = errors.New("unknown key ID")
+)
+
+// signingKey is an asymmetric key tokens are signed or verified with. Private is nil for keys
+// that are only kept to verify tokens signed before a rotation.
+type signingKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// keySet holds the keys of the service. Without asymmetric keys, tokens are signed with HS256 and secret.
+// With them, tokens signed with secret are only accepted until secretUntil.
+type keySet struct {
+	secret       []byte
+	secretUntil  time.Time
+	signing      *signingKey
+	verification map[string]*signingKey
+}
+
+var keys = &keySet{
+	secret:       []byte(os.Getenv("JWT_SECRET")),
+	verification: map[string]*signingKey{},
+}
+
+// LoadKeys signs new tokens with the private key in signingKeyFile, and keeps verifying tokens signed with
+// the keys in verificationKeyFiles, which hold the private or public keys used before a rotation.
+// Tokens signed with JWT_SECRET are only accepted until secretAcceptedUntil, a zero time rejects them right away.
+func LoadKeys(signingKeyFile string, verificationKeyFiles []string, secretAcceptedUntil time.Time) error {
+	signing, err := readKey(signingKeyFile)
+	if err != nil {
+		return fmt.Errorf("signing key %s: %w", signingKeyFile, err)
+	}
+	if signing.Private == nil {
+		return fmt.Errorf("signing key %s: %w", signingKeyFile, ErrUnsupportedKey)
+	}
+
+	verification := map[string]*signingKey{signing.ID: signing}
+	for _, file := range verificationKeyFiles {
+		key, err := readKey(file)
+		if err != nil {
+			return fmt.Errorf("verification key %s: %w", file, err)
+		}
+		verification[key.ID] = key
+	}
+
+	keys = &keySet{
+		secret:       keys.secret,
+		secretUntil:  secretAcceptedUntil,
+		signing:      signing,
+		verification: verification,
+	}
+	return nil
+}
+
+func readKey(file string) (*signingKey, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, k.Public()
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	key.ID = thumbprint(toJWK(key))
+	return key, nil
+}
+
+// JWK is the public part of a key in the JSON Web Key format of RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func toJWK(key *signingKey) JWK {
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}
+	}
+	return JWK{}
+}
+
+// thumbprint computes the RFC 7638 thumbprint of the key, used as its ID so that it is stable across restarts.
+func thumbprint(jwk JWK) string {
+	// the thumbprint is computed over the required members only, in lexicographic order
+	var members any
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	b, _ := json.Marshal(members)
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -2,14 +2,29 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
+	"github.com/citadel-corp/segokuning-social-app/internal/common/auth"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/jwt"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
 )
 
+// ContextAuthKey holds the Principal of an authenticated request.
 type ContextAuthKey struct{}
 
+// SessionValidator reports whether the session an access token was issued for is still active.
+type SessionValidator func(ctx context.Context, sessionID string) (bool, error)
+
+var sessionValidator SessionValidator
+
+// SetSessionValidator registers the validator used to reject access tokens of revoked sessions.
+func SetSessionValidator(validator SessionValidator) {
+	sessionValidator = validator
+}
+
 func Authorized(next func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -31,19 +46,41 @@ func Authorized(next func(w http.ResponseWriter, r *http.Request)) func(w http.R
 			return
 		}
 
-		subject, err := jwt.VerifyAndGetSubject(tokenString)
+		claims, err := jwt.Verify(tokenString)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), ContextAuthKey{}, subject)
+		if !isSessionActive(r.Context(), claims.SessionID) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ContextAuthKey{}, newPrincipal(claims))
 		r = r.WithContext(ctx)
 
 		next(w, r)
 	}
 }
 
+// RequireScopes authorizes the request like Authorized, and refuses it with 403 unless
+// its access token grants every scope.
+func RequireScopes(next func(w http.ResponseWriter, r *http.Request), scopes ...string) func(w http.ResponseWriter, r *http.Request) {
+	return Authorized(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := PrincipalFromContext(r.Context())
+		if !ok || !principal.HasScopes(scopes...) {
+			response.JSON(w, http.StatusForbidden, response.ResponseBody{
+				Message: "Forbidden",
+				Error:   fmt.Sprintf("missing scopes: %s", strings.Join(scopes, " ")),
+			})
+			return
+		}
+
+		next(w, r)
+	})
+}
+
 // Authenticate request only if authorization header is set
 func Authenticate(next func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -66,16 +103,50 @@ func Authenticate(next func(w http.ResponseWriter, r *http.Request)) func(w http
 			return
 		}
 
-		subject, err := jwt.VerifyAndGetSubject(tokenString)
+		claims, err := jwt.Verify(tokenString)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
-			slog.InfoContext(r.Context(), "Invalid token: %v", err)
+			slog.InfoContext(r.Context(), "Invalid token", slog.String("error", err.Error()))
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), ContextAuthKey{}, subject)
+		if !isSessionActive(r.Context(), claims.SessionID) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ContextAuthKey{}, newPrincipal(claims))
 		r = r.WithContext(ctx)
 
 		next(w, r)
 	}
 }
+
+func newPrincipal(claims *jwt.Claims) Principal {
+	// tokens signed before roles were added to them belong to regular users
+	if claims.Roles == nil {
+		claims.Roles = []string{auth.RoleUser}
+		claims.Scopes = auth.ScopesFor(claims.Roles)
+	}
+	return Principal{
+		UserID:    claims.Subject,
+		SessionID: claims.SessionID,
+		Roles:     claims.Roles,
+		Scopes:    claims.Scopes,
+	}
+}
+
+func isSessionActive(ctx context.Context, sessionID string) bool {
+	if sessionID == "" {
+		return false
+	}
+	if sessionValidator == nil {
+		return true
+	}
+	active, err := sessionValidator(ctx, sessionID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Cannot validate session", slog.String("error", err.Error()))
+		return false
+	}
+	return active
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/request"
+)
+
+type ContextClientKey struct{}
+
+// Client describes where a request comes from.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// ClientInfo stores the Client of the request in its context.
+func ClientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ContextClientKey{}, Client{
+			IP:        request.ClientIP(r),
+			UserAgent: r.UserAgent(),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientFromContext returns the Client stored by ClientInfo, or an empty Client outside of a request.
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(ContextClientKey{}).(Client)
+	return client
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+)
+
+// Principal is the authenticated user of a request, as described by its access token.
+type Principal struct {
+	UserID    string
+	SessionID string
+	Roles     []string
+	Scopes    []string
+}
+
+// HasScopes reports whether the principal was granted every scope.
+func (p Principal) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(p.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// PrincipalFromContext returns the Principal stored by Authorized, Authenticate or RequireScopes.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(ContextAuthKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+type Channel string
+
+var (
+	ChannelEmail Channel = "email"
+	ChannelPhone Channel = "phone"
+)
+
+type Message struct {
+	Channel Channel `json:"channel"`
+	To      string  `json:"to"`
+	Subject string  `json:"subject"`
+	Body    string  `json:"body"`
+}
+
+// Notifier delivers messages to the email address or phone number of a user.
+type Notifier interface {
+	Send(ctx context.Context, message Message) error
+}
+
+type logNotifier struct{}
+
+// NewLog returns a Notifier that only logs the messages, for local development.
+func NewLog() Notifier {
+	return &logNotifier{}
+}
+
+// Send implements Notifier.
+func (n *logNotifier) Send(ctx context.Context, message Message) error {
+	slog.InfoContext(ctx, fmt.Sprintf("Notification to %s %s: %s", message.Channel, message.To, message.Body))
+	return nil
+}
+
+type fileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile returns a Notifier that appends the messages to a file as JSON lines, for local development and CI.
+func NewFile(path string) Notifier {
+	return &fileNotifier{path: path}
+}
+
+// Send implements Notifier.
+func (n *fileNotifier) Send(ctx context.Context, message Message) error {
+	line, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sentAt"`
+	}{Message: message, SentAt: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,97 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+type argon2idParams struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// currentArgon2idParams follow the OWASP recommendation, hashes made with other parameters are rehashed on login.
+var currentArgon2idParams = argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// argon2idSlots bounds how many argon2id keys are derived at once, each holds Memory KiB until it is done,
+// so that a burst of logins waits for a slot instead of exhausting the memory of the service.
+var argon2idSlots = make(chan struct{}, runtime.NumCPU())
+
+func idKey(password []byte, salt []byte, p argon2idParams) []byte {
+	argon2idSlots <- struct{}{}
+	defer func() { <-argon2idSlots }()
+	return argon2.IDKey(password, salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+}
+
+func hashArgon2id(plaintextPassword string, p argon2idParams) (string, error) {
+	salt := make([]byte, p.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+	key := idKey([]byte(plaintextPassword), salt, p)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func matchesArgon2id(plaintextPassword, hashedPassword string) (bool, bool, error) {
+	p, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return false, false, err
+	}
+	otherKey := idKey([]byte(plaintextPassword), salt, p)
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return false, false, nil
+	}
+	return true, p != currentArgon2idParams, nil
+}
+
+func decodeArgon2id(hashedPassword string) (argon2idParams, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=65536,t=3,p=2", salt, key
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 {
+		return argon2idParams{}, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil {
+		return argon2idParams{}, nil, nil, ErrUnknownHashFormat
+	}
+	if version != argon2.Version {
+		return argon2idParams{}, nil, nil, fmt.Errorf("%w: argon2 version %d", ErrUnknownHashFormat, version)
+	}
+
+	var p argon2idParams
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
+	if err != nil {
+		return argon2idParams{}, nil, nil, ErrUnknownHashFormat
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2idParams{}, nil, nil, ErrUnknownHashFormat
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return argon2idParams{}, nil, nil, ErrUnknownHashFormat
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -2,30 +2,19 @@ package password
 
 import (
 	"errors"
-	"os"
-	"strconv"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-var (
-	costStr = os.Getenv("BCRYPT_SALT")
-)
-
-func Hash(plaintextPassword string) (string, error) {
-	cost, err := strconv.Atoi(costStr)
-	if err != nil {
-		return "", err
-	}
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), cost)
-	if err != nil {
-		return "", err
-	}
-
-	return string(hashedPassword), nil
+// isBcrypt reports whether the hash was made by bcrypt, which is only kept to verify passwords
+// hashed before argon2id was introduced.
+func isBcrypt(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") || strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
 }
 
-func Matches(plaintextPassword, hashedPassword string) (bool, error) {
+func matchesBcrypt(plaintextPassword, hashedPassword string) (bool, error) {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(plaintextPassword))
 	if err != nil {
 		switch {
@@ -0,0 +1,47 @@
+// Package password hashes passwords into self-describing PHC strings such as
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>. New hashes always use argon2id,
+// bcrypt hashes of older accounts are still verified and reported for rehashing.
+package password
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownHashFormat = errors.New("unknown password hash format")
+)
+
+// Hash returns the argon2id hash of the password with the current parameters.
+func Hash(plaintextPassword string) (string, error) {
+	return hashArgon2id(plaintextPassword, currentArgon2idParams)
+}
+
+// Matches reports whether the password matches the hash, and whether the hash should be replaced by
+// a new one from Hash because it uses an outdated algorithm or parameters.
+func Matches(plaintextPassword, hashedPassword string) (match bool, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(hashedPassword, "$argon2id$"):
+		return matchesArgon2id(plaintextPassword, hashedPassword)
+	case isBcrypt(hashedPassword):
+		match, err = matchesBcrypt(plaintextPassword, hashedPassword)
+		return match, true, err
+	default:
+		return false, false, ErrUnknownHashFormat
+	}
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// CompareDummy takes as long as Matches does for an existing user, so that a login for an unknown
+// credential cannot be told apart by its response time.
+func CompareDummy(plaintextPassword string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = Hash("dummy password")
+	})
+	_, _, _ = Matches(plaintextPassword, dummyHash)
+}
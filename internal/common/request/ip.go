@@ -0,0 +1,38 @@
+package request
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	// the number of proxies in front of the service, each appending the address it received the request from to
+	// X-Forwarded-For. The hops left of those are set by the client and can be forged, so they are never read.
+	trustedProxyCount, _ = strconv.Atoi(os.Getenv("TRUSTED_PROXY_COUNT"))
+)
+
+// ClientIP returns the IP address of the client that sent the request.
+func ClientIP(r *http.Request) string {
+	if trustedProxyCount > 0 {
+		hops := []string{}
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(header, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		// the request did not come through every proxy when there are fewer hops than proxies
+		if len(hops) >= trustedProxyCount {
+			if ip := net.ParseIP(hops[len(hops)-trustedProxyCount]); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
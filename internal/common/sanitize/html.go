@@ -0,0 +1,44 @@
+package sanitize
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	htmlPolicy = newHTMLPolicy()
+	textPolicy = bluemonday.StrictPolicy()
+
+	blockTags   = regexp.MustCompile(`(?i)<\s*(br|/p)\s*/?>`)
+	whitespaces = regexp.MustCompile(`[ \t]+`)
+	newlines    = regexp.MustCompile(`\s*\n\s*`)
+)
+
+// newHTMLPolicy allows paragraphs, line breaks, bold/italic text and links that are marked as nofollow.
+func newHTMLPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "b", "strong", "i", "em")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowStandardURLs()
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// HTML strips every element and attribute that is not in the allow-list.
+func HTML(s string) string {
+	return strings.TrimSpace(htmlPolicy.Sanitize(s))
+}
+
+// Text returns the plain text rendition of a HTML fragment.
+func Text(s string) string {
+	s = blockTags.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(textPolicy.Sanitize(s))
+	s = whitespaces.ReplaceAllString(s, " ")
+	s = newlines.ReplaceAllString(s, "\n")
+	return strings.TrimSpace(s)
+}
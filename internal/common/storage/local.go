@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalPathPrefix is the route the local storage files are served from.
+const LocalPathPrefix = "/static/"
+
+// localMaxUploadSize caps the body of a presigned upload to the local storage.
+const localMaxUploadSize = 10 * 1024 * 1024
+
+// Local stores blobs in a directory of the local filesystem, for development and CI.
+type Local struct {
+	dir     string
+	baseURL string
+	secret  []byte
+}
+
+// NewLocal returns a Local storage writing into dir, with URLs built from the service base URL.
+// The secret signs the presigned upload URLs.
+func NewLocal(dir string, baseURL string, secret string) (*Local, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	return &Local{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  []byte(secret),
+	}, nil
+}
+
+// Put implements Storage.
+func (l *Local) Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) (string, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, body)
+	if err != nil {
+		return "", err
+	}
+
+	return l.URL(key), nil
+}
+
+// Get implements Storage.
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// PresignPut implements Storage, the URL is handled by UploadHandler.
+func (l *Local) PresignPut(ctx context.Context, key string, contentType string, size int64, expires time.Duration) (string, error) {
+	if _, err := l.path(key); err != nil {
+		return "", err
+	}
+	if size <= 0 || size > localMaxUploadSize {
+		return "", fmt.Errorf("invalid upload size %d", size)
+	}
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	sizeString := strconv.FormatInt(size, 10)
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	query.Set("size", sizeString)
+	query.Set("signature", l.sign(key, contentType, sizeString, expiresAt))
+	return l.URL(key) + "?" + query.Encode(), nil
+}
+
+// Delete implements Storage.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	// drop the directory of the key once it is empty, it fails harmlessly otherwise
+	if dir := filepath.Dir(path); dir != filepath.Clean(l.dir) {
+		_ = os.Remove(dir)
+	}
+	return nil
+}
+
+// Exists implements Storage.
+func (l *Local) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return false, nil
+	}
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// URL implements Storage.
+func (l *Local) URL(key string) string {
+	return l.baseURL + LocalPathPrefix + key
+}
+
+// Handler serves the stored files, it is meant to be mounted on LocalPathPrefix.
+func (l *Local) Handler() http.Handler {
+	files := http.StripPrefix(LocalPathPrefix, http.FileServer(http.Dir(l.dir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// do not list directories
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// UploadFilter reports whether a presigned upload can still be written to the key, so that a URL that has not
+// expired yet cannot write again once its upload was used.
+type UploadFilter func(ctx context.Context, key string) (bool, error)
+
+// UploadHandler stores the body of presigned PUT requests the filter accepts, it is meant to be mounted on LocalPathPrefix.
+// The filter is asked again once the body is stored, and the body is removed when the key was closed in the meantime.
+func (l *Local) UploadHandler(accepts UploadFilter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, LocalPathPrefix)
+		expiresAt := r.URL.Query().Get("expires")
+		expires, err := strconv.ParseInt(expiresAt, 10, 64)
+		if err != nil || time.Now().Unix() > expires {
+			http.Error(w, "upload URL has expired", http.StatusForbidden)
+			return
+		}
+		sizeString := r.URL.Query().Get("size")
+		signature := l.sign(key, r.Header.Get("Content-Type"), sizeString, expiresAt)
+		if !hmac.Equal([]byte(signature), []byte(r.URL.Query().Get("signature"))) {
+			http.Error(w, "upload URL signature does not match", http.StatusForbidden)
+			return
+		}
+		// the size is signed, like the content length of a presigned S3 upload
+		size, err := strconv.ParseInt(sizeString, 10, 64)
+		if err != nil || r.ContentLength != size {
+			http.Error(w, "body does not have the signed size", http.StatusForbidden)
+			return
+		}
+
+		ok, err := accepts(r.Context(), key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "upload URL has already been used", http.StatusForbidden)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, min(size, localMaxUploadSize))
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if int64(len(body)) != size {
+			http.Error(w, "body does not have the signed size", http.StatusBadRequest)
+			return
+		}
+		_, err = l.Put(r.Context(), key, bytes.NewReader(body), r.Header.Get("Content-Type"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ok, err = accepts(r.Context(), key)
+		if err != nil || !ok {
+			_ = l.Delete(r.Context(), key)
+			http.Error(w, "upload URL has already been used", http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (l *Local) sign(key string, contentType string, size string, expiresAt string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + contentType + "\n" + size + "\n" + expiresAt))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *Local) path(key string) (string, error) {
+	name := filepath.FromSlash(key)
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(l.dir, name), nil
+}
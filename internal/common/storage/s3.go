@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type s3Storage struct {
+	client *s3.S3
+	bucket string
+}
+
+// NewS3 returns a Storage that uploads public-read objects to the S3 bucket.
+func NewS3(awsSession *session.Session, bucket string) Storage {
+	return &s3Storage{
+		client: s3.New(awsSession),
+		bucket: bucket,
+	}
+}
+
+// Put implements Storage.
+func (s *s3Storage) Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) (string, error) {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		ACL:         aws.String("public-read"),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.URL(key), nil
+}
+
+// Get implements Storage.
+func (s *s3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// PresignPut implements Storage. The content length is a signed header, S3 refuses a body of any other size.
+func (s *s3Storage) PresignPut(ctx context.Context, key string, contentType string, size int64, expires time.Duration) (string, error) {
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+	})
+	req.SetContext(ctx)
+	return req.Presign(expires)
+}
+
+// Delete implements Storage.
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// Exists implements Storage.
+func (s *s3Storage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// URL implements Storage.
+func (s *s3Storage) URL(key string) string {
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	// building only resolves the endpoint, the request is never sent
+	_ = req.Build()
+	return req.HTTPRequest.URL.String()
+}
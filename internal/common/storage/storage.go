@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Storage keeps uploaded blobs and tells where they can be downloaded from.
+type Storage interface {
+	// Put stores the blob under the key and returns its public URL.
+	Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) (string, error)
+	// Get opens the blob stored under the key, it returns ErrNotFound when there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// PresignPut returns a URL the client can PUT a blob of the content type and of exactly size bytes to
+	// until it expires.
+	PresignPut(ctx context.Context, key string, contentType string, size int64, expires time.Duration) (string, error)
+	// Delete removes the blob stored under the key, deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// Exists reports whether a blob is stored under the key.
+	Exists(ctx context.Context, key string) (bool, error)
+	// URL returns the public URL of the key, whether or not a blob is stored under it.
+	URL(key string) string
+}
@@ -0,0 +1,60 @@
+package totp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the base32 encoding of the SHA1 seed "12345678901234567890" of the RFC 6238 test vectors.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// rfc6238Vectors are the SHA1 test vectors of RFC 6238 appendix B. The RFC gives 8 digit codes, the
+// 6 digit codes authenticator apps show are their last 6 digits.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestRFC6238Vectors(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		t.Run(fmt.Sprintf("time %d", v.unix), func(t *testing.T) {
+			at := time.Unix(v.unix, 0)
+			code := v.code[len(v.code)-Digits:]
+
+			step, ok := Validate(code, rfc6238Secret, at)
+			if !ok {
+				t.Fatalf("code %s is not valid at %d", code, v.unix)
+			}
+			if step != Step(at) {
+				t.Errorf("code %s validated for step %d, want %d", code, step, Step(at))
+			}
+			// a code is accepted one period away for clock drift, and no further
+			if _, ok := Validate(code, rfc6238Secret, at.Add(Period)); !ok {
+				t.Errorf("code %s is not valid one period later", code)
+			}
+			if _, ok := Validate(code, rfc6238Secret, at.Add(2*Period)); ok {
+				t.Errorf("code %s is still valid two periods later", code)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	at := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870822", "94287082"} {
+		if _, ok := Validate(code, rfc6238Secret, at); ok {
+			t.Errorf("code %q is valid", code)
+		}
+	}
+	if _, ok := Validate("287082", "not base32!", at); ok {
+		t.Error("code is valid with a malformed secret")
+	}
+}
@@ -0,0 +1,143 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	stdimage "image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/webp"
+)
+
+// maxPixels bounds the decoded size of an upload, a small file can still declare huge dimensions.
+const maxPixels = 50_000_000
+
+// formats are the accepted upload formats, keyed by their sniffed content type.
+var formats = map[string]struct {
+	decode       func(r io.Reader) (stdimage.Image, error)
+	decodeConfig func(r io.Reader) (stdimage.Config, error)
+}{
+	"image/jpeg": {decode: jpeg.Decode, decodeConfig: jpeg.DecodeConfig},
+	"image/png":  {decode: png.Decode, decodeConfig: png.DecodeConfig},
+	"image/webp": {decode: webp.Decode, decodeConfig: webp.DecodeConfig},
+	"image/gif":  {decode: decodeStaticGIF, decodeConfig: gif.DecodeConfig},
+}
+
+// decode detects the image format from the leading bytes of the file, not from what the client claims,
+// and fully decodes it so that truncated or malformed files are refused.
+func decode(data []byte) (stdimage.Image, string, error) {
+	contentType := http.DetectContentType(data)
+	format, ok := formats[contentType]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedImage, contentType)
+	}
+
+	config, err := format.decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, "", fmt.Errorf("%w: dimensions %dx%d are not allowed", ErrInvalidImage, config.Width, config.Height)
+	}
+
+	img, err := format.decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+
+	return img, contentType, nil
+}
+
+// decodeStaticGIF refuses animated GIFs by counting their frames before anything is decoded,
+// then decodes the only frame.
+func decodeStaticGIF(r io.Reader) (stdimage.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	frames, err := countGIFFrames(data, 2)
+	if err != nil {
+		return nil, err
+	}
+	if frames != 1 {
+		return nil, fmt.Errorf("%w: animated GIF", ErrUnsupportedImage)
+	}
+	return gif.Decode(bytes.NewReader(data))
+}
+
+// countGIFFrames walks the blocks of a GIF without decompressing them, and stops counting image
+// descriptors once it reaches limit.
+func countGIFFrames(data []byte, limit int) (int, error) {
+	errMalformed := errors.New("gif: malformed block structure")
+
+	// header and logical screen descriptor
+	if len(data) < 13 {
+		return 0, errMalformed
+	}
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1)
+	}
+
+	// skipSubBlocks moves past a sequence of data sub-blocks and its terminator
+	skipSubBlocks := func() error {
+		for {
+			if pos >= len(data) {
+				return errMalformed
+			}
+			size := int(data[pos])
+			pos++
+			if size == 0 {
+				return nil
+			}
+			pos += size
+		}
+	}
+
+	frames := 0
+	for frames < limit {
+		if pos >= len(data) {
+			return 0, errMalformed
+		}
+		switch data[pos] {
+		case 0x21: // extension: introducer, label, sub-blocks
+			pos += 2
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+		case 0x2C: // image descriptor, optional local color table, LZW minimum code size, sub-blocks
+			frames++
+			if pos+10 > len(data) {
+				return 0, errMalformed
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos++
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+		case 0x3B: // trailer
+			return frames, nil
+		default:
+			return 0, errMalformed
+		}
+	}
+	return frames, nil
+}
+
+// encode writes the variant as a JPEG, except for formats that may carry transparency which are kept as PNG.
+func encode(w io.Writer, img stdimage.Image, sourceType string, quality int) (string, error) {
+	if sourceType == "image/jpeg" {
+		return "image/jpeg", jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	}
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	return "image/png", encoder.Encode(w, img)
+}
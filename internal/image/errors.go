@@ -0,0 +1,14 @@
+package image
+
+import "errors"
+
+var (
+	ErrInvalidImage          = errors.New("file is not a valid image")
+	ErrUnsupportedImage      = errors.New("file must be a JPEG, PNG, WebP or static GIF image")
+	ErrImageNotFound         = errors.New("image not found")
+	ErrImageInUse            = errors.New("image is in use")
+	ErrImageNotUploaded      = errors.New("image has not been uploaded")
+	ErrImageAlreadyConfirmed = errors.New("image upload is already confirmed")
+	ErrQuotaExceeded         = errors.New("upload quota exceeded")
+	ErrValidationFailed      = errors.New("validation failed")
+)
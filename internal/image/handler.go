@@ -1,10 +1,14 @@
 package image
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
-	"strings"
 
+	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/request"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/gorilla/mux"
 )
 
 type Handler struct {
@@ -15,7 +19,16 @@ func NewHandler(service Service) *Handler {
 	return &Handler{service: service}
 }
 
-func (h *Handler) UploadToS3(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	r.Body = http.MaxBytesReader(w, r.Body, 2*1024*1024) // 2 MB
 
 	if err := r.ParseMultipartForm(2 * 1024 * 1024); err != nil {
@@ -47,29 +60,178 @@ func (h *Handler) UploadToS3(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer file.Close()
-	mimeType := header.Header.Get("Content-Type")
-	if mimeType != "image/jpeg" {
-		fileNameSplit := strings.Split(header.Filename, ".")
-		ext := fileNameSplit[len(fileNameSplit)-1]
-		isJPEG := ext == "jpeg" || ext == "jpg"
-		if mimeType == "application/octet-stream" && !isJPEG {
-			response.JSON(w, http.StatusBadRequest, response.ResponseBody{
-				Message: "File is not a jpg/jpeg type",
-			})
-			return
-		}
-	}
-
-	resp, err := h.service.UploadToS3(r.Context(), file)
+
+	resp, err := h.service.Upload(r.Context(), file, userID)
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "File uploaded successfully",
+		Data:    resp,
+	})
+}
+
+func (h *Handler) CreateUploadURL(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var req UploadURLPayload
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.service.CreateUploadURL(r.Context(), req, userID)
+	if errors.Is(err, ErrQuotaExceeded) {
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: "Too many requests",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
-			Message: "Unable to upload file",
+			Message: "Internal server error",
 			Error:   err.Error(),
 		})
 		return
 	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Upload URL created successfully",
+		Data:    resp,
+	})
+}
+
+func (h *Handler) Confirm(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.service.Confirm(r.Context(), mux.Vars(r)["imageId"], userID)
+	if errors.Is(err, ErrImageNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrImageAlreadyConfirmed) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrImageNotUploaded) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
 	response.JSON(w, http.StatusOK, response.ResponseBody{
 		Message: "File uploaded successfully",
 		Data:    resp,
 	})
 }
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	err = h.service.Delete(r.Context(), mux.Vars(r)["imageId"], userID)
+	if errors.Is(err, ErrImageNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrImageInUse) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Image deleted successfully",
+	})
+}
+
+// writeUploadError responds to a failure while processing an uploaded file.
+func writeUploadError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrQuotaExceeded) {
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: "Too many requests",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrUnsupportedImage) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "File type is not supported",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrInvalidImage) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "File is not a valid image",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+		Message: "Unable to upload file",
+		Error:   err.Error(),
+	})
+}
+
+func getUserID(r *http.Request) (string, error) {
+	if principal, ok := middleware.PrincipalFromContext(r.Context()); ok {
+		return principal.UserID, nil
+	}
+	slog.Error("cannot parse auth value from context")
+	return "", errors.New("cannot parse auth value from context")
+}
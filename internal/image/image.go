@@ -0,0 +1,69 @@
+package image
+
+import "time"
+
+type Status string
+
+var (
+	// StatusPending images were handed a presigned upload URL and wait for their upload to be confirmed.
+	StatusPending Status = "pending"
+	StatusReady   Status = "ready"
+)
+
+// Image is an upload tracked in the database. Its variants are stored under keys derived from Key.
+type Image struct {
+	ID           string
+	UserID       string
+	Key          string
+	ContentType  string
+	Size         int64
+	UploadSize   int64
+	Width        int
+	Height       int
+	Hash         string
+	ReferencedBy *string
+	Status       Status
+	CreatedAt    time.Time
+}
+
+// Quota bounds what a user can upload over the last 24 hours, zero values disable a bound.
+type Quota struct {
+	DailyCount int
+	DailyBytes int64
+}
+
+// UserReference is recorded on the image used as the profile picture of the user.
+func UserReference(userID string) string {
+	return "user:" + userID
+}
+
+// PostReference is recorded on the image attached to the post.
+func PostReference(postID string) string {
+	return "post:" + postID
+}
+
+// Variant is a resized rendition of an uploaded image.
+type Variant struct {
+	Name    string
+	MaxSize int
+	Quality int
+}
+
+// Variants are generated for every upload, from the smallest to the largest.
+// The largest variant is the canonical rendition of the image.
+var Variants = []Variant{
+	{Name: "small", MaxSize: 64, Quality: 75},
+	{Name: "medium", MaxSize: 256, Quality: 80},
+	{Name: "large", MaxSize: 1024, Quality: 85},
+}
+
+// originalKey is the storage key a presigned upload is written to, before its variants are generated.
+func originalKey(key string) string {
+	return key + "/original"
+}
+
+// variantKey is the storage key of a variant of the image. Keys carry no extension since variants
+// are stored as JPEG or PNG depending on the upload, the stored content type tells them apart.
+func variantKey(imageID string, v Variant) string {
+	return imageID + "/" + v.Name
+}
@@ -0,0 +1,241 @@
+package image
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+)
+
+type Repository interface {
+	Create(ctx context.Context, image *Image) error
+	GetByID(ctx context.Context, id string) (*Image, error)
+	GetByHash(ctx context.Context, userID string, hash string) (*Image, error)
+	RecordUpload(ctx context.Context, userID string, size int64, since time.Time, quota Quota) error
+	PruneUploads(ctx context.Context, createdBefore time.Time) error
+	MarkReady(ctx context.Context, image *Image) error
+	Reference(ctx context.Context, id string, reference string) error
+	ReleaseReference(ctx context.Context, reference string) error
+	Delete(ctx context.Context, id string) error
+	ListOrphans(ctx context.Context, createdBefore time.Time, limit int) ([]Image, error)
+}
+
+type dbRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+// Create implements Repository.
+func (d *dbRepository) Create(ctx context.Context, image *Image) error {
+	row := d.db.DB().QueryRowContext(ctx, `
+			INSERT INTO images (
+				id, user_id, key, content_type, size, upload_size, width, height, hash, status
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+			)
+			RETURNING created_at
+		`, image.ID, image.UserID, image.Key, image.ContentType, image.Size, image.UploadSize, image.Width, image.Height,
+		image.Hash, image.Status)
+	return row.Scan(&image.CreatedAt)
+}
+
+// GetByID implements Repository.
+func (d *dbRepository) GetByID(ctx context.Context, id string) (*Image, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT id, user_id, key, content_type, size, upload_size, width, height, hash, referenced_by, status, created_at
+		FROM images
+		WHERE id = $1;
+	`, id)
+
+	i := &Image{}
+	err := row.Scan(&i.ID, &i.UserID, &i.Key, &i.ContentType, &i.Size, &i.UploadSize, &i.Width, &i.Height, &i.Hash, &i.ReferencedBy, &i.Status, &i.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// GetByHash returns the ready and unreferenced image of the user with the same content hash.
+func (d *dbRepository) GetByHash(ctx context.Context, userID string, hash string) (*Image, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT id, user_id, key, content_type, size, upload_size, width, height, hash, referenced_by, status, created_at
+		FROM images
+		WHERE user_id = $1 AND hash = $2 AND status = $3 AND referenced_by IS NULL
+		ORDER BY created_at
+		LIMIT 1;
+	`, userID, hash, StatusReady)
+
+	i := &Image{}
+	err := row.Scan(&i.ID, &i.UserID, &i.Key, &i.ContentType, &i.Size, &i.UploadSize, &i.Width, &i.Height, &i.Hash,
+		&i.ReferencedBy, &i.Status, &i.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// RecordUpload adds an upload of the given size to the ledger of the user, unless it would exceed the quota
+// over the uploads recorded since the given time. Uploads of the same user are recorded one at a time,
+// so that concurrent uploads cannot all fit in the same remaining quota.
+func (d *dbRepository) RecordUpload(ctx context.Context, userID string, size int64, since time.Time, quota Quota) error {
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+				SELECT pg_advisory_xact_lock(hashtext('image_uploads:' || $1::text))
+			`, userID)
+		if err != nil {
+			return err
+		}
+
+		var (
+			count int
+			bytes int64
+		)
+		row := tx.QueryRowContext(ctx, `
+				SELECT COUNT(*), COALESCE(SUM(size), 0)
+				FROM image_uploads
+				WHERE user_id = $1 AND created_at >= $2
+			`, userID, since)
+		err = row.Scan(&count, &bytes)
+		if err != nil {
+			return err
+		}
+		if quota.DailyCount > 0 && count+1 > quota.DailyCount {
+			return fmt.Errorf("%w: at most %d images per day", ErrQuotaExceeded, quota.DailyCount)
+		}
+		if quota.DailyBytes > 0 && bytes+size > quota.DailyBytes {
+			return fmt.Errorf("%w: at most %d bytes per day", ErrQuotaExceeded, quota.DailyBytes)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+				INSERT INTO image_uploads (
+					user_id, size
+				) VALUES (
+					$1, $2
+				)
+			`, userID, size)
+		return err
+	})
+
+	return err
+}
+
+// PruneUploads removes the ledger entries that no longer count towards any quota.
+func (d *dbRepository) PruneUploads(ctx context.Context, createdBefore time.Time) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		DELETE FROM image_uploads
+		WHERE created_at < $1;
+	`, createdBefore)
+	return err
+}
+
+// MarkReady stores what was learnt about a confirmed upload.
+func (d *dbRepository) MarkReady(ctx context.Context, image *Image) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		UPDATE images
+		SET content_type = $1,
+		size = $2,
+		upload_size = $3,
+		width = $4,
+		height = $5,
+		hash = $6,
+		status = $7
+		WHERE id = $8;
+	`, image.ContentType, image.Size, image.UploadSize, image.Width, image.Height, image.Hash, StatusReady, image.ID)
+	if err != nil {
+		return err
+	}
+	image.Status = StatusReady
+	return nil
+}
+
+// Reference moves the reference to the image, the image that held it before becomes unreferenced.
+// It returns ErrImageInUse when the image is held by another reference.
+func (d *dbRepository) Reference(ctx context.Context, id string, reference string) error {
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+				UPDATE images
+				SET referenced_by = $1
+				WHERE id = $2 AND (referenced_by IS NULL OR referenced_by = $1)
+			`, reference, id)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrImageInUse
+		}
+
+		_, err = tx.ExecContext(ctx, `
+				UPDATE images
+				SET referenced_by = NULL
+				WHERE referenced_by = $1 AND id != $2
+			`, reference, id)
+		return err
+	})
+
+	return err
+}
+
+// ReleaseReference implements Repository.
+func (d *dbRepository) ReleaseReference(ctx context.Context, reference string) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		UPDATE images
+		SET referenced_by = NULL
+		WHERE referenced_by = $1;
+	`, reference)
+	return err
+}
+
+// Delete implements Repository.
+func (d *dbRepository) Delete(ctx context.Context, id string) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		DELETE FROM images
+		WHERE id = $1;
+	`, id)
+	return err
+}
+
+// ListOrphans returns the oldest unreferenced images created before the given time.
+func (d *dbRepository) ListOrphans(ctx context.Context, createdBefore time.Time, limit int) ([]Image, error) {
+	rows, err := d.db.DB().QueryContext(ctx, `
+		SELECT id, user_id, key, content_type, size, upload_size, width, height, hash, referenced_by, status, created_at
+		FROM images
+		WHERE referenced_by IS NULL AND created_at < $1
+		ORDER BY created_at
+		LIMIT $2;
+	`, createdBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []Image{}
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Key, &i.ContentType, &i.Size, &i.UploadSize, &i.Width, &i.Height, &i.Hash, &i.ReferencedBy, &i.Status, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		images = append(images, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
@@ -0,0 +1,25 @@
+package image
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	minUploadSize = 10 * 1024
+	// maxDirectUploadSize caps the presigned uploads, which do not go through the API servers.
+	maxDirectUploadSize = 10 * 1024 * 1024
+)
+
+var uploadContentTypes = []interface{}{"image/jpeg", "image/png", "image/webp", "image/gif"}
+
+type UploadURLPayload struct {
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+func (p UploadURLPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ContentType, validation.Required, validation.In(uploadContentTypes...)),
+		validation.Field(&p.Size, validation.Required, validation.Min(int64(minUploadSize)), validation.Max(int64(maxDirectUploadSize))),
+	)
+}
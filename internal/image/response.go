@@ -1,5 +1,24 @@
 package image
 
+import "time"
+
 type ImageResponse struct {
+	ID       string            `json:"imageId"`
+	ImageURL string            `json:"imageUrl"`
+	Variants []VariantResponse `json:"variants"`
+}
+
+type VariantResponse struct {
+	Name     string `json:"name"`
 	ImageURL string `json:"imageUrl"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+type UploadURLResponse struct {
+	ImageID   string            `json:"imageId"`
+	UploadURL string            `json:"uploadUrl"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expiresAt"`
 }
@@ -1,58 +1,416 @@
 package image
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	stdimage "image"
 	"io"
-	"os"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/storage"
 	"github.com/google/uuid"
-)
-
-var (
-	bucket = os.Getenv("S3_BUCKET_NAME")
-	key    = os.Getenv("S3_SECRET_KEY")
+	"golang.org/x/image/draw"
 )
 
 type Service interface {
-	UploadToS3(ctx context.Context, readSeeker io.ReadSeeker) (*ImageResponse, error)
+	Upload(ctx context.Context, readSeeker io.ReadSeeker, userID string) (*ImageResponse, error)
+	CreateUploadURL(ctx context.Context, req UploadURLPayload, userID string) (*UploadURLResponse, error)
+	Confirm(ctx context.Context, imageID string, userID string) (*ImageResponse, error)
+	AcceptsUpload(ctx context.Context, key string) (bool, error)
+	Reference(ctx context.Context, imageID string, userID string, reference string) (string, error)
+	ReleaseReference(ctx context.Context, reference string) error
+	Delete(ctx context.Context, imageID string, userID string) error
+	Sweep(ctx context.Context, grace time.Duration) (int, error)
 }
 
+const (
+	// sweepBatchSize is the number of orphaned images removed per query while sweeping.
+	sweepBatchSize = 100
+	// uploadURLTTL is how long a presigned upload URL can be used.
+	uploadURLTTL = 15 * time.Minute
+	// quotaWindow is the period the daily quota applies to.
+	quotaWindow = 24 * time.Hour
+)
+
 type imageService struct {
-	awsSession *session.Session
+	repository Repository
+	storage    storage.Storage
+	quota      Quota
 }
 
-func NewService(awsSession *session.Session) Service {
+func NewService(repository Repository, storage storage.Storage, quota Quota) Service {
 	return &imageService{
-		awsSession: awsSession,
+		repository: repository,
+		storage:    storage,
+		quota:      quota,
 	}
 }
 
-func (s *imageService) UploadToS3(ctx context.Context, readSeeker io.ReadSeeker) (*ImageResponse, error) {
-	svc := s3.New(s.awsSession)
-	filename := uuid.NewString()
-	// This uploads the contents of the buffer to S3
-	_, err := svc.PutObject(&s3.PutObjectInput{
-		Bucket:      aws.String(bucket),
-		Key:         aws.String(filename),
-		ACL:         aws.String("public-read"),
-		Body:        readSeeker,
-		ContentType: aws.String("image/jpeg"),
-	})
+// Upload stores a resized and re-encoded copy of the image for every variant. Only the pixels are
+// re-encoded, so EXIF metadata such as the GPS location never reaches the storage.
+// Uploading the same bytes again returns the image the user already has, unless it is already in use.
+func (s *imageService) Upload(ctx context.Context, readSeeker io.ReadSeeker, userID string) (*ImageResponse, error) {
+	data, err := io.ReadAll(readSeeker)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashData(data)
+	existing, err := s.repository.GetByHash(ctx, userID, hash)
+	if err == nil {
+		return s.response(existing), nil
+	}
+	if !errors.Is(err, ErrImageNotFound) {
+		return nil, err
+	}
+
+	err = s.checkQuota(ctx, userID, int64(len(data)))
 	if err != nil {
 		return nil, err
 	}
-	params := &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(filename),
+
+	image := &Image{
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		Hash:       hash,
+		UploadSize: int64(len(data)),
+		Status:     StatusReady,
 	}
+	image.Key = image.ID
 
-	req, _ := svc.GetObjectRequest(params)
-	req.Build()
+	err = s.storeVariants(ctx, image, data)
+	if err != nil {
+		return nil, err
+	}
 
-	return &ImageResponse{
-		ImageURL: req.HTTPRequest.URL.String(),
+	err = s.repository.Create(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.response(image), nil
+}
+
+// CreateUploadURL registers a pending image and returns a presigned URL the client uploads it to directly.
+// The declared size counts towards the quota of the user until the upload is confirmed.
+func (s *imageService) CreateUploadURL(ctx context.Context, req UploadURLPayload, userID string) (*UploadURLResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+
+	err = s.checkQuota(ctx, userID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	image := &Image{
+		ID:          uuid.NewString(),
+		UserID:      userID,
+		ContentType: req.ContentType,
+		UploadSize:  req.Size,
+		Status:      StatusPending,
+	}
+	image.Key = image.ID
+
+	expiresAt := time.Now().Add(uploadURLTTL)
+	uploadURL, err := s.storage.PresignPut(ctx, originalKey(image.Key), req.ContentType, req.Size, uploadURLTTL)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repository.Create(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadURLResponse{
+		ImageID:   image.ID,
+		UploadURL: uploadURL,
+		Method:    http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type":   req.ContentType,
+			"Content-Length": strconv.FormatInt(req.Size, 10),
+		},
+		ExpiresAt: expiresAt,
 	}, nil
 }
+
+// Confirm validates an image uploaded through a presigned URL and generates its variants.
+// The uploaded original is removed once the variants are stored, it may still carry metadata.
+func (s *imageService) Confirm(ctx context.Context, imageID string, userID string) (*ImageResponse, error) {
+	image, err := s.getOwnImage(ctx, imageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if image.Status != StatusPending {
+		return nil, ErrImageAlreadyConfirmed
+	}
+
+	original, err := s.storage.Get(ctx, originalKey(image.Key))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrImageNotUploaded
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer original.Close()
+
+	declaredSize := image.UploadSize
+	data, err := io.ReadAll(io.LimitReader(original, declaredSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	image.Hash = hashData(data)
+	image.UploadSize = int64(len(data))
+	existing, err := s.repository.GetByHash(ctx, userID, image.Hash)
+	if err == nil {
+		// the user already has these bytes, the pending image is not needed
+		return s.response(existing), s.remove(ctx, image)
+	}
+	if !errors.Is(err, ErrImageNotFound) {
+		return nil, err
+	}
+
+	switch {
+	case image.UploadSize > declaredSize:
+		err = fmt.Errorf("%w: file is larger than the declared %d bytes", ErrInvalidImage, declaredSize)
+	case image.UploadSize < minUploadSize:
+		err = fmt.Errorf("%w: file is smaller than %d bytes", ErrInvalidImage, minUploadSize)
+	default:
+		err = s.storeVariants(ctx, image, data)
+	}
+	if errors.Is(err, ErrInvalidImage) || errors.Is(err, ErrUnsupportedImage) {
+		// the upload cannot be confirmed anymore, the client has to ask for a new URL
+		if removeErr := s.remove(ctx, image); removeErr != nil {
+			return nil, removeErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the image is ready before its original is deleted, so that AcceptsUpload refuses to write it again
+	err = s.repository.MarkReady(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	err = s.storage.Delete(ctx, originalKey(image.Key))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.response(image), nil
+}
+
+// AcceptsUpload reports whether a presigned upload can be written to the storage key, which is only the case
+// for the original of an image that is still pending. Presigned images are keyed by their ID.
+func (s *imageService) AcceptsUpload(ctx context.Context, key string) (bool, error) {
+	imageKey, ok := strings.CutSuffix(key, "/original")
+	if !ok {
+		return false, nil
+	}
+	image, err := s.repository.GetByID(ctx, imageKey)
+	if errors.Is(err, ErrImageNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return image.Status == StatusPending && image.Key == imageKey, nil
+}
+
+// Reference records that the image of the user is now used by the reference, and returns the URL of
+// its canonical rendition. The image previously used by the same reference is released, and an image
+// used by another reference returns ErrImageInUse.
+func (s *imageService) Reference(ctx context.Context, imageID string, userID string, reference string) (string, error) {
+	image, err := s.getOwnImage(ctx, imageID, userID)
+	if err != nil {
+		return "", err
+	}
+	if image.Status != StatusReady {
+		return "", ErrImageNotFound
+	}
+	err = s.repository.Reference(ctx, image.ID, reference)
+	if err != nil {
+		return "", err
+	}
+	return s.storage.URL(variantKey(image.Key, Variants[len(Variants)-1])), nil
+}
+
+// ReleaseReference implements Service.
+func (s *imageService) ReleaseReference(ctx context.Context, reference string) error {
+	return s.repository.ReleaseReference(ctx, reference)
+}
+
+// Delete removes an image of the user that is not in use.
+func (s *imageService) Delete(ctx context.Context, imageID string, userID string) error {
+	image, err := s.getOwnImage(ctx, imageID, userID)
+	if err != nil {
+		return err
+	}
+	if image.ReferencedBy != nil {
+		return ErrImageInUse
+	}
+	return s.remove(ctx, image)
+}
+
+// Sweep removes the unreferenced images that are older than the grace period, which leaves
+// clients the time to reference an image after uploading it. It returns the number of removed images.
+func (s *imageService) Sweep(ctx context.Context, grace time.Duration) (int, error) {
+	var removed int
+	err := s.repository.PruneUploads(ctx, time.Now().Add(-quotaWindow))
+	if err != nil {
+		return removed, err
+	}
+	createdBefore := time.Now().Add(-grace)
+	for {
+		orphans, err := s.repository.ListOrphans(ctx, createdBefore, sweepBatchSize)
+		if err != nil {
+			return removed, err
+		}
+		for i := range orphans {
+			err = s.remove(ctx, &orphans[i])
+			if err != nil {
+				return removed, err
+			}
+			removed++
+		}
+		if len(orphans) < sweepBatchSize {
+			return removed, nil
+		}
+	}
+}
+
+// remove deletes the stored variants before the record, so that a failure leaves the image to be swept again.
+func (s *imageService) remove(ctx context.Context, image *Image) error {
+	err := s.storage.Delete(ctx, originalKey(image.Key))
+	if err != nil {
+		return err
+	}
+	for _, v := range Variants {
+		err := s.storage.Delete(ctx, variantKey(image.Key, v))
+		if err != nil {
+			return err
+		}
+	}
+	return s.repository.Delete(ctx, image.ID)
+}
+
+// storeVariants decodes the image data and stores its variants, filling the content type, dimensions
+// and stored size of the image.
+func (s *imageService) storeVariants(ctx context.Context, image *Image, data []byte) error {
+	src, sourceType, err := decode(data)
+	if err != nil {
+		return err
+	}
+	orientation := 1
+	if sourceType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	image.Width, image.Height = src.Bounds().Dx(), src.Bounds().Dy()
+	if orientation >= 5 {
+		image.Width, image.Height = image.Height, image.Width
+	}
+	image.Size = 0
+
+	for _, v := range Variants {
+		dst := orient(resize(src, v.MaxSize), orientation)
+
+		var buf bytes.Buffer
+		contentType, err := encode(&buf, dst, sourceType, v.Quality)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.storage.Put(ctx, variantKey(image.Key, v), bytes.NewReader(buf.Bytes()), contentType)
+		if err != nil {
+			return err
+		}
+		image.ContentType = contentType
+		image.Size += int64(buf.Len())
+	}
+
+	return nil
+}
+
+// checkQuota refuses an upload of the given size when it would exceed the daily quota of the user, and
+// counts it otherwise. An upload stays counted when its image is deleted, swept or turns out to be invalid.
+func (s *imageService) checkQuota(ctx context.Context, userID string, size int64) error {
+	if s.quota.DailyCount <= 0 && s.quota.DailyBytes <= 0 {
+		return nil
+	}
+	return s.repository.RecordUpload(ctx, userID, size, time.Now().Add(-quotaWindow), s.quota)
+}
+
+func (s *imageService) response(image *Image) *ImageResponse {
+	resp := &ImageResponse{
+		ID:       image.ID,
+		Variants: make([]VariantResponse, 0, len(Variants)),
+	}
+	for _, v := range Variants {
+		width, height := fit(image.Width, image.Height, v.MaxSize)
+		url := s.storage.URL(variantKey(image.Key, v))
+		resp.Variants = append(resp.Variants, VariantResponse{
+			Name:     v.Name,
+			ImageURL: url,
+			Width:    width,
+			Height:   height,
+		})
+		resp.ImageURL = url
+	}
+	return resp
+}
+
+// getOwnImage returns the image only when it was uploaded by the user.
+func (s *imageService) getOwnImage(ctx context.Context, imageID string, userID string) (*Image, error) {
+	if _, err := uuid.Parse(imageID); err != nil {
+		return nil, ErrImageNotFound
+	}
+	image, err := s.repository.GetByID(ctx, imageID)
+	if err != nil {
+		return nil, err
+	}
+	if image.UserID != userID {
+		return nil, ErrImageNotFound
+	}
+	return image, nil
+}
+
+func hashData(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// fit returns the dimensions scaled down so that neither side exceeds maxSize, keeping the aspect ratio.
+func fit(w int, h int, maxSize int) (int, int) {
+	if w <= maxSize && h <= maxSize {
+		return w, h
+	}
+	if w >= h {
+		return maxSize, max(1, h*maxSize/w)
+	}
+	return max(1, w*maxSize/h), maxSize
+}
+
+// resize scales the image down so that neither side exceeds maxSize, smaller images are kept as is.
+func resize(src stdimage.Image, maxSize int) stdimage.Image {
+	b := src.Bounds()
+	w, h := fit(b.Dx(), b.Dy(), maxSize)
+	if w == b.Dx() && h == b.Dy() {
+		return src
+	}
+
+	dst := stdimage.NewRGBA(stdimage.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,401 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	stdimage "image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/storage"
+)
+
+var update = flag.Bool("update", false, "regenerate the JPEG fixtures in testdata")
+
+const (
+	fixtureWidth  = 48
+	fixtureHeight = 24
+)
+
+var (
+	red   = color.RGBA{R: 255, A: 255}
+	green = color.RGBA{G: 255, A: 255}
+	blue  = color.RGBA{B: 255, A: 255}
+	white = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+// upright is how every fixture must look once displayed: a landscape image with red, green, blue and white
+// quadrants from the top-left to the bottom-right.
+var upright = [4]color.RGBA{red, green, blue, white}
+
+// storedQuadrants are the quadrants, top-left to bottom-right, of the pixels stored in a fixture with each
+// EXIF orientation, so that a viewer honouring the orientation displays the upright image.
+var storedQuadrants = map[int][4]color.RGBA{
+	1: {red, green, blue, white},
+	2: {green, red, white, blue},
+	3: {white, blue, green, red},
+	4: {blue, white, red, green},
+	5: {red, blue, green, white},
+	6: {green, white, red, blue},
+	7: {white, green, blue, red},
+	8: {blue, red, white, green},
+}
+
+// TestUploadStripsMetadata uploads photos carrying GPS coordinates with every EXIF orientation, and checks
+// that the stored variants carry no EXIF block and are rotated upright.
+func TestUploadStripsMetadata(t *testing.T) {
+	for orientation := 1; orientation <= 8; orientation++ {
+		t.Run(fmt.Sprintf("orientation %d", orientation), func(t *testing.T) {
+			fixture := readFixture(t, orientation)
+			if !hasGPS(fixture) {
+				t.Fatal("fixture carries no GPS coordinates")
+			}
+			if got := jpegOrientation(fixture); got != orientation {
+				t.Fatalf("fixture orientation = %d, want %d", got, orientation)
+			}
+
+			dir := t.TempDir()
+			blobs, err := storage.NewLocal(dir, "http://localhost", "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			service := NewService(newMemoryRepository(), blobs, Quota{})
+
+			resp, err := service.Upload(context.Background(), bytes.NewReader(fixture), "user")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, v := range Variants {
+				stored, err := os.ReadFile(filepath.Join(dir, resp.ID, v.Name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hasExifSegment(stored) {
+					t.Errorf("%s variant still carries an APP1/Exif segment", v.Name)
+				}
+				if bytes.Contains(stored, []byte("Exif\x00\x00")) {
+					t.Errorf("%s variant still contains an EXIF header", v.Name)
+				}
+
+				img, err := jpeg.Decode(bytes.NewReader(stored))
+				if err != nil {
+					t.Fatal(err)
+				}
+				b := img.Bounds()
+				if b.Dx() != fixtureWidth || b.Dy() != fixtureHeight {
+					t.Fatalf("%s variant is %dx%d, want %dx%d", v.Name, b.Dx(), b.Dy(), fixtureWidth, fixtureHeight)
+				}
+				for i, want := range upright {
+					if got := quadrantColor(img, i); !closeTo(got, want) {
+						t.Errorf("%s variant quadrant %d = %v, want %v", v.Name, i, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestPresignedUploadIsSingleUse checks that a presigned upload URL that has not expired cannot write the
+// original again once the upload was confirmed.
+func TestPresignedUploadIsSingleUse(t *testing.T) {
+	// trailing bytes after the end of image marker are ignored by the decoder
+	fixture := append(readFixture(t, 1), make([]byte, minUploadSize)...)
+
+	dir := t.TempDir()
+	blobs, err := storage.NewLocal(dir, "http://localhost", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewService(newMemoryRepository(), blobs, Quota{})
+	uploads := blobs.UploadHandler(service.AcceptsUpload)
+
+	resp, err := service.CreateUploadURL(context.Background(), UploadURLPayload{ContentType: "image/jpeg", Size: int64(len(fixture))}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func() int {
+		r := httptest.NewRequest(http.MethodPut, resp.UploadURL, bytes.NewReader(fixture))
+		for name, value := range resp.Headers {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		uploads.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := put(); code != http.StatusOK {
+		t.Fatalf("upload answered %d, want %d", code, http.StatusOK)
+	}
+	_, err = service.Confirm(context.Background(), resp.ImageID, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := put(); code != http.StatusForbidden {
+		t.Errorf("upload after confirmation answered %d, want %d", code, http.StatusForbidden)
+	}
+	if _, err := os.Stat(filepath.Join(dir, originalKey(resp.ImageID))); !os.IsNotExist(err) {
+		t.Errorf("original is stored again after confirmation: %v", err)
+	}
+}
+
+func readFixture(t *testing.T, orientation int) []byte {
+	t.Helper()
+	path := filepath.Join("testdata", fmt.Sprintf("gps_orientation_%d.jpg", orientation))
+	if *update {
+		err := os.WriteFile(path, newFixture(t, orientation), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// newFixture encodes a JPEG whose pixels are stored with the orientation, with an EXIF block carrying
+// the orientation and GPS coordinates inserted right after the start of image marker.
+func newFixture(t *testing.T, orientation int) []byte {
+	t.Helper()
+	w, h := fixtureWidth, fixtureHeight
+	if orientation >= 5 {
+		w, h = h, w
+	}
+	quadrants := storedQuadrants[orientation]
+	img := stdimage.NewRGBA(stdimage.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := 0
+			if x >= w/2 {
+				i++
+			}
+			if y >= h/2 {
+				i += 2
+			}
+			img.Set(x, y, quadrants[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+
+	exif := append([]byte("Exif\x00\x00"), exifTIFF(orientation)...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(exif)+2))
+	segment = append(segment, exif...)
+
+	fixture := append([]byte{}, encoded[:2]...)
+	fixture = append(fixture, segment...)
+	return append(fixture, encoded[2:]...)
+}
+
+// exifTIFF builds a little-endian TIFF block with the orientation and a GPS IFD pointing at
+// 52°31'12"N 13°24'36"E.
+func exifTIFF(orientation int) []byte {
+	const (
+		ifd0Offset      = 8
+		ifd0Entries     = 2
+		gpsOffset       = ifd0Offset + 2 + ifd0Entries*12 + 4
+		gpsEntries      = 4
+		latitudeOffset  = gpsOffset + 2 + gpsEntries*12 + 4
+		longitudeOffset = latitudeOffset + 24
+	)
+	order := binary.LittleEndian
+	tiff := []byte("II*\x00")
+	tiff = order.AppendUint32(tiff, ifd0Offset)
+
+	entry := func(tag uint16, kind uint16, count uint32, value uint32) {
+		tiff = order.AppendUint16(tiff, tag)
+		tiff = order.AppendUint16(tiff, kind)
+		tiff = order.AppendUint32(tiff, count)
+		if kind == 3 {
+			// a SHORT value is left-justified in the value field
+			tiff = order.AppendUint16(tiff, uint16(value))
+			tiff = order.AppendUint16(tiff, 0)
+			return
+		}
+		tiff = order.AppendUint32(tiff, value)
+	}
+	ascii := func(c byte) uint32 {
+		return uint32(c)
+	}
+
+	tiff = order.AppendUint16(tiff, ifd0Entries)
+	entry(exifOrientationTag, 3, 1, uint32(orientation))
+	entry(0x8825, 4, 1, gpsOffset) // GPSInfo
+	tiff = order.AppendUint32(tiff, 0)
+
+	tiff = order.AppendUint16(tiff, gpsEntries)
+	entry(0x0001, 2, 2, ascii('N')) // GPSLatitudeRef
+	entry(0x0002, 5, 3, latitudeOffset)
+	entry(0x0003, 2, 2, ascii('E')) // GPSLongitudeRef
+	entry(0x0004, 5, 3, longitudeOffset)
+	tiff = order.AppendUint32(tiff, 0)
+
+	for _, v := range []uint32{52, 31, 12, 13, 24, 36} {
+		tiff = order.AppendUint32(tiff, v)
+		tiff = order.AppendUint32(tiff, 1)
+	}
+	return tiff
+}
+
+// jpegSegments returns the markers and payloads of the segments before the start of scan.
+func jpegSegments(data []byte) map[byte][][]byte {
+	segments := map[byte][][]byte{}
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		if marker == 0xDA {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if i+2+length > len(data) {
+			break
+		}
+		segments[marker] = append(segments[marker], data[i+4:i+2+length])
+		i += 2 + length
+	}
+	return segments
+}
+
+func hasExifSegment(data []byte) bool {
+	return len(jpegSegments(data)[0xE1]) > 0
+}
+
+// hasGPS reports whether the EXIF block of the JPEG points at a GPS IFD.
+func hasGPS(data []byte) bool {
+	for _, segment := range jpegSegments(data)[0xE1] {
+		if bytes.HasPrefix(segment, []byte("Exif\x00\x00")) && bytes.Contains(segment, []byte{0x25, 0x88, 0x04, 0x00}) {
+			return true
+		}
+	}
+	return false
+}
+
+// quadrantColor samples the center of a quadrant, numbered from the top-left to the bottom-right.
+func quadrantColor(img stdimage.Image, quadrant int) color.RGBA {
+	b := img.Bounds()
+	x := b.Min.X + b.Dx()/4
+	y := b.Min.Y + b.Dy()/4
+	if quadrant%2 == 1 {
+		x += b.Dx() / 2
+	}
+	if quadrant >= 2 {
+		y += b.Dy() / 2
+	}
+	r, g, bl, a := img.At(x, y).RGBA()
+	return color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(bl >> 8), A: uint8(a >> 8)}
+}
+
+// closeTo compares colors with room for JPEG compression artifacts.
+func closeTo(a color.RGBA, b color.RGBA) bool {
+	near := func(x uint8, y uint8) bool {
+		d := int(x) - int(y)
+		return d > -48 && d < 48
+	}
+	return near(a.R, b.R) && near(a.G, b.G) && near(a.B, b.B)
+}
+
+// memoryRepository keeps images in memory, for tests that do not need a database.
+type memoryRepository struct {
+	images map[string]Image
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{images: map[string]Image{}}
+}
+
+func (m *memoryRepository) Create(ctx context.Context, image *Image) error {
+	image.CreatedAt = time.Now()
+	m.images[image.ID] = *image
+	return nil
+}
+
+func (m *memoryRepository) GetByID(ctx context.Context, id string) (*Image, error) {
+	image, ok := m.images[id]
+	if !ok {
+		return nil, ErrImageNotFound
+	}
+	return &image, nil
+}
+
+func (m *memoryRepository) GetByHash(ctx context.Context, userID string, hash string) (*Image, error) {
+	for _, image := range m.images {
+		if image.UserID == userID && image.Hash == hash && image.Status == StatusReady && image.ReferencedBy == nil {
+			return &image, nil
+		}
+	}
+	return nil, ErrImageNotFound
+}
+
+func (m *memoryRepository) RecordUpload(ctx context.Context, userID string, size int64, since time.Time, quota Quota) error {
+	return nil
+}
+
+func (m *memoryRepository) PruneUploads(ctx context.Context, createdBefore time.Time) error {
+	return nil
+}
+
+func (m *memoryRepository) MarkReady(ctx context.Context, image *Image) error {
+	image.Status = StatusReady
+	m.images[image.ID] = *image
+	return nil
+}
+
+func (m *memoryRepository) Reference(ctx context.Context, id string, reference string) error {
+	image, ok := m.images[id]
+	if !ok {
+		return ErrImageNotFound
+	}
+	if image.ReferencedBy != nil && *image.ReferencedBy != reference {
+		return ErrImageInUse
+	}
+	for key, other := range m.images {
+		if other.ReferencedBy != nil && *other.ReferencedBy == reference {
+			other.ReferencedBy = nil
+			m.images[key] = other
+		}
+	}
+	image.ReferencedBy = &reference
+	m.images[id] = image
+	return nil
+}
+
+func (m *memoryRepository) ReleaseReference(ctx context.Context, reference string) error {
+	for key, image := range m.images {
+		if image.ReferencedBy != nil && *image.ReferencedBy == reference {
+			image.ReferencedBy = nil
+			m.images[key] = image
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepository) Delete(ctx context.Context, id string) error {
+	delete(m.images, id)
+	return nil
+}
+
+func (m *memoryRepository) ListOrphans(ctx context.Context, createdBefore time.Time, limit int) ([]Image, error) {
+	orphans := []Image{}
+	for _, image := range m.images {
+		if image.ReferencedBy == nil && image.CreatedAt.Before(createdBefore) && len(orphans) < limit {
+			orphans = append(orphans, image)
+		}
+	}
+	return orphans, nil
+}
@@ -0,0 +1,31 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// StartSweeper periodically removes orphaned images until the context is done.
+func StartSweeper(ctx context.Context, service Service, interval time.Duration, grace time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				removed, err := service.Sweep(ctx, grace)
+				if err != nil {
+					slog.Error(fmt.Sprintf("Image sweep failed: %v", err))
+				}
+				if removed > 0 {
+					slog.Info(fmt.Sprintf("Image sweep removed %d orphaned images", removed))
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,24 @@
+package loginattempt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrTooManyAttempts = errors.New("too many failed login attempts")
+)
+
+// LockedError is returned while a key has to wait before its next login attempt.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, try again in %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
@@ -0,0 +1,83 @@
+package loginattempt
+
+import (
+	"math"
+	"time"
+)
+
+type Scope string
+
+var (
+	ScopeCredential Scope = "credential"
+	ScopeIP         Scope = "ip"
+	ScopeTwoFactor  Scope = "two_factor"
+	ScopePassword   Scope = "password"
+)
+
+// Key identifies what failed login attempts are counted against.
+type Key struct {
+	Scope Scope
+	Value string
+}
+
+func CredentialKey(credentialType string, credentialValue string) Key {
+	return Key{Scope: ScopeCredential, Value: credentialType + ":" + credentialValue}
+}
+
+func IPKey(ip string) Key {
+	return Key{Scope: ScopeIP, Value: ip}
+}
+
+// TwoFactorKey counts the wrong second factor codes given for the user.
+func TwoFactorKey(userID string) Key {
+	return Key{Scope: ScopeTwoFactor, Value: userID}
+}
+
+// PasswordKey counts the wrong current passwords given by the user while signed in.
+func PasswordKey(userID string) Key {
+	return Key{Scope: ScopePassword, Value: userID}
+}
+
+// Failures counts the consecutive failed login attempts of a key.
+type Failures struct {
+	Key          Key
+	Count        int
+	LastFailedAt time.Time
+}
+
+// Policy decides how long a key has to wait before its next login attempt.
+// The first FreeFailures failures are not throttled, every following failure doubles the delay starting
+// from BaseDelay, and reaching MaxFailures locks the key out for Lockout. Failures are forgotten
+// once ResetAfter has passed since the last one.
+type Policy struct {
+	FreeFailures int
+	BaseDelay    time.Duration
+	MaxFailures  int
+	Lockout      time.Duration
+	ResetAfter   time.Duration
+}
+
+var policies = map[Scope]Policy{
+	ScopeCredential: {FreeFailures: 3, BaseDelay: time.Second, MaxFailures: 10, Lockout: 15 * time.Minute, ResetAfter: time.Hour},
+	// an IP can be shared by many users, so it is given more room before being throttled
+	ScopeIP:        {FreeFailures: 20, BaseDelay: time.Second, MaxFailures: 100, Lockout: time.Hour, ResetAfter: time.Hour},
+	ScopeTwoFactor: {FreeFailures: 3, BaseDelay: time.Second, MaxFailures: 10, Lockout: 15 * time.Minute, ResetAfter: time.Hour},
+	// a stolen session must not be a way around the credential policy to guess the password
+	ScopePassword: {FreeFailures: 3, BaseDelay: time.Second, MaxFailures: 10, Lockout: 15 * time.Minute, ResetAfter: time.Hour},
+}
+
+// blockedUntil returns when the key may attempt to log in again.
+func (p Policy) blockedUntil(f *Failures) time.Time {
+	switch {
+	case f.Count >= p.MaxFailures:
+		return f.LastFailedAt.Add(p.Lockout)
+	case f.Count > p.FreeFailures:
+		delay := p.BaseDelay * time.Duration(math.Pow(2, float64(f.Count-p.FreeFailures-1)))
+		if delay > p.Lockout {
+			delay = p.Lockout
+		}
+		return f.LastFailedAt.Add(delay)
+	default:
+		return f.LastFailedAt
+	}
+}
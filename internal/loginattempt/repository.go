@@ -0,0 +1,108 @@
+package loginattempt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+)
+
+type Repository interface {
+	RecordAttempt(ctx context.Context, key Key, at time.Time, policy Policy) (time.Time, error)
+	Refund(ctx context.Context, key Key, policy Policy) error
+	Reset(ctx context.Context, key Key) error
+}
+
+type dbRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+// RecordAttempt counts an attempt of the key as a failure, starting over when the previous failure is older than
+// policy.ResetAfter. When the key is blocked at that time, nothing is counted and the time it is blocked until is returned.
+// The row stays locked until blocked_until is updated, so that concurrent attempts are counted one after the other.
+func (d *dbRepository) RecordAttempt(ctx context.Context, key Key, at time.Time, policy Policy) (time.Time, error) {
+	var blockedUntil time.Time
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO login_failures (
+				scope, value, failures, last_failed_at, blocked_until
+			) VALUES (
+				$1, $2, 0, $3, $3
+			)
+			ON CONFLICT (scope, value) DO NOTHING;
+		`, key.Scope, key.Value, at)
+		if err != nil {
+			return err
+		}
+
+		f := &Failures{Key: key, LastFailedAt: at}
+		row := tx.QueryRowContext(ctx, `
+			UPDATE login_failures
+			SET failures = CASE WHEN last_failed_at < $4 THEN 1 ELSE failures + 1 END,
+				last_failed_at = $3
+			WHERE scope = $1 AND value = $2 AND blocked_until <= $3
+			RETURNING failures;
+		`, key.Scope, key.Value, at, at.Add(-policy.ResetAfter))
+		err = row.Scan(&f.Count)
+		if errors.Is(err, sql.ErrNoRows) {
+			return tx.QueryRowContext(ctx, `
+				SELECT blocked_until
+				FROM login_failures
+				WHERE scope = $1 AND value = $2;
+			`, key.Scope, key.Value).Scan(&blockedUntil)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE login_failures
+			SET blocked_until = $3
+			WHERE scope = $1 AND value = $2;
+		`, key.Scope, key.Value, policy.blockedUntil(f))
+		return err
+	})
+	return blockedUntil, err
+}
+
+// Refund takes back an attempt counted by RecordAttempt.
+func (d *dbRepository) Refund(ctx context.Context, key Key, policy Policy) error {
+	return d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		f := &Failures{Key: key}
+		row := tx.QueryRowContext(ctx, `
+			UPDATE login_failures
+			SET failures = GREATEST(failures - 1, 0)
+			WHERE scope = $1 AND value = $2
+			RETURNING failures, last_failed_at;
+		`, key.Scope, key.Value)
+		err := row.Scan(&f.Count, &f.LastFailedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE login_failures
+			SET blocked_until = $3
+			WHERE scope = $1 AND value = $2;
+		`, key.Scope, key.Value, policy.blockedUntil(f))
+		return err
+	})
+}
+
+// Reset implements Repository.
+func (d *dbRepository) Reset(ctx context.Context, key Key) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		DELETE FROM login_failures
+		WHERE scope = $1 AND value = $2;
+	`, key.Scope, key.Value)
+	return err
+}
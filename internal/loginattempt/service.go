@@ -0,0 +1,53 @@
+package loginattempt
+
+import (
+	"context"
+	"time"
+)
+
+type Service interface {
+	Attempt(ctx context.Context, keys ...Key) error
+	Refund(ctx context.Context, keys ...Key) error
+	Reset(ctx context.Context, key Key) error
+}
+
+type loginAttemptService struct {
+	repository Repository
+}
+
+func NewService(repository Repository) Service {
+	return &loginAttemptService{repository: repository}
+}
+
+// Attempt counts a login attempt against the keys in order before it is checked, and returns a *LockedError as soon
+// as a key has to wait before its next attempt, leaving the following keys uncounted. The attempt counts as a failed
+// one until it is taken back by Reset or Refund, so that concurrent attempts cannot all pass before the first of them fails.
+func (s *loginAttemptService) Attempt(ctx context.Context, keys ...Key) error {
+	now := time.Now()
+	for _, key := range keys {
+		blockedUntil, err := s.repository.RecordAttempt(ctx, key, now, policies[key.Scope])
+		if err != nil {
+			return err
+		}
+		if retryAfter := blockedUntil.Sub(now); retryAfter > 0 {
+			return &LockedError{RetryAfter: retryAfter}
+		}
+	}
+	return nil
+}
+
+// Refund takes back a successful attempt from keys that are not reset by a success, such as an IP shared with others.
+func (s *loginAttemptService) Refund(ctx context.Context, keys ...Key) error {
+	for _, key := range keys {
+		err := s.repository.Refund(ctx, key, policies[key.Scope])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset forgets the failed attempts of the key.
+func (s *loginAttemptService) Reset(ctx context.Context, key Key) error {
+	return s.repository.Reset(ctx, key)
+}
@@ -1,6 +1,7 @@
 package posts
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -8,6 +9,8 @@ import (
 	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/request"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/citadel-corp/segokuning-social-app/internal/reactions"
+	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
 )
 
@@ -58,8 +61,66 @@ func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-func (h *Handler) CreatePostComment(w http.ResponseWriter, r *http.Request) {
-	var req CreatePostCommentPayload
+func (h *Handler) ListPost(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req ListPostPayload
+
+	newSchema := schema.NewDecoder()
+	newSchema.IgnoreUnknownKeys(true)
+	if err := newSchema.Decode(&req, r.URL.Query()); err != nil {
+		slog.Error(err.Error())
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{})
+		return
+	}
+
+	var params = r.URL.Query()
+	if v, ok := request.CheckPositiveInt(params, "limit"); ok {
+		req.Limit = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if v, ok := request.CheckPositiveInt(params, "offset"); ok {
+		req.Offset = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	req.UserID = userID
+
+	resp := h.service.List(r.Context(), req)
+	response.JSON(w, resp.Code, response.ResponseBody{
+		Message: resp.Message,
+		Data:    resp.Data,
+		Meta:    resp.Meta,
+		Error:   resp.Error,
+	})
+}
+
+func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) {
+	h.handlePost(w, r, h.service.Get)
+}
+
+func (h *Handler) DeletePost(w http.ResponseWriter, r *http.Request) {
+	h.handlePost(w, r, h.service.Delete)
+}
+
+func (h *Handler) ListPostRevisions(w http.ResponseWriter, r *http.Request) {
+	h.handlePost(w, r, h.service.ListRevisions)
+}
+
+func (h *Handler) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	var req UpdatePostPayload
 	var resp Response
 	var err error
 
@@ -70,14 +131,18 @@ func (h *Handler) CreatePostComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req.UserID = userID
-
 	err = request.DecodeJSON(w, r, &req)
 	if err != nil {
 		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
 			Message: "Failed to decode JSON",
+			Error:   err.Error(),
 		})
+		return
 	}
+
+	req.UserID = userID
+	req.PostID = mux.Vars(r)["postId"]
+
 	err = req.Validate()
 	if err != nil {
 		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
@@ -86,14 +151,34 @@ func (h *Handler) CreatePostComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp = h.service.CreatePostComment(r.Context(), req)
+	resp = h.service.Update(r.Context(), req)
 	response.JSON(w, resp.Code, response.ResponseBody{
 		Message: resp.Message,
 		Error:   resp.Error,
 	})
 }
 
-func (h *Handler) ListPost(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ReactToPost(w http.ResponseWriter, r *http.Request) {
+	h.react(w, r, reactions.TargetPost, mux.Vars(r)["postId"])
+}
+
+func (h *Handler) ReactToComment(w http.ResponseWriter, r *http.Request) {
+	h.react(w, r, reactions.TargetComment, mux.Vars(r)["commentId"])
+}
+
+func (h *Handler) ListPostReactions(w http.ResponseWriter, r *http.Request) {
+	h.listReactions(w, r, reactions.TargetPost, mux.Vars(r)["postId"])
+}
+
+func (h *Handler) ListCommentReactions(w http.ResponseWriter, r *http.Request) {
+	h.listReactions(w, r, reactions.TargetComment, mux.Vars(r)["commentId"])
+}
+
+func (h *Handler) react(w http.ResponseWriter, r *http.Request, targetType reactions.TargetType, targetID string) {
+	var req ReactPayload
+	var resp Response
+	var err error
+
 	userID, err := getUserID(r)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -101,16 +186,49 @@ func (h *Handler) ListPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req ListPostPayload
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
 
-	newSchema := schema.NewDecoder()
-	newSchema.IgnoreUnknownKeys(true)
-	if err := newSchema.Decode(&req, r.URL.Query()); err != nil {
-		slog.Error(err.Error())
-		response.JSON(w, http.StatusBadRequest, response.ResponseBody{})
+	req.UserID = userID
+	req.TargetType = targetType
+	req.TargetID = targetID
+
+	err = req.Validate()
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	resp = h.service.React(r.Context(), req)
+	response.JSON(w, resp.Code, response.ResponseBody{
+		Message: resp.Message,
+		Data:    resp.Data,
+		Error:   resp.Error,
+	})
+}
+
+func (h *Handler) listReactions(w http.ResponseWriter, r *http.Request, targetType reactions.TargetType, targetID string) {
+	userID, err := getUserID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
+	req := ListReactionPayload{
+		UserID:     userID,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+
 	var params = r.URL.Query()
 	if v, ok := request.CheckPositiveInt(params, "limit"); ok {
 		req.Limit = v
@@ -128,9 +246,17 @@ func (h *Handler) ListPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req.UserID = userID
+	req.Kind = params.Get("kind")
 
-	resp := h.service.List(r.Context(), req)
+	err = req.Validate()
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	resp := h.service.ListReactions(r.Context(), req)
 	response.JSON(w, resp.Code, response.ResponseBody{
 		Message: resp.Message,
 		Data:    resp.Data,
@@ -139,9 +265,38 @@ func (h *Handler) ListPost(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request, handle func(ctx context.Context, req GetPostPayload) Response) {
+	userID, err := getUserID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	req := GetPostPayload{
+		UserID: userID,
+		PostID: mux.Vars(r)["postId"],
+	}
+
+	err = req.Validate()
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	resp := handle(r.Context(), req)
+	response.JSON(w, resp.Code, response.ResponseBody{
+		Message: resp.Message,
+		Data:    resp.Data,
+		Error:   resp.Error,
+	})
+}
+
 func getUserID(r *http.Request) (string, error) {
-	if authValue, ok := r.Context().Value(middleware.ContextAuthKey{}).(string); ok {
-		return authValue, nil
+	if principal, ok := middleware.PrincipalFromContext(r.Context()); ok {
+		return principal.UserID, nil
 	}
 
 	return "", errors.New("unauthorized")
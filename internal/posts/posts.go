@@ -3,17 +3,20 @@ package posts
 import "time"
 
 type Posts struct {
-	ID        string
-	UserID    string
-	Content   string
-	Tags      []string
-	CreatedAt time.Time
+	ID          string
+	UserID      string
+	Content     string
+	ContentText string
+	Tags        []string
+	ImageURL    *string
+	CreatedAt   time.Time
+	UpdatedAt   *time.Time
 }
 
-type Comment struct {
+type Revision struct {
 	ID        uint64
-	UserID    string
 	PostID    string
 	Content   string
+	Tags      []string
 	CreatedAt time.Time
 }
@@ -5,8 +5,10 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/citadel-corp/segokuning-social-app/internal/comments"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/cursor"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
 	"github.com/citadel-corp/segokuning-social-app/internal/user"
@@ -17,8 +19,21 @@ import (
 type Repository interface {
 	Create(ctx context.Context, post *Posts) error
 	GetByID(ctx context.Context, id string) (*Posts, error)
-	CreateComment(ctx context.Context, comment *Comment) error
+	Update(ctx context.Context, post *Posts) error
+	UpdateImage(ctx context.Context, id string, imageURL *string) error
+	Delete(ctx context.Context, id string) error
+	ListRevisions(ctx context.Context, postID string) ([]RevisionResponse, error)
 	List(ctx context.Context, filter ListPostPayload) ([]ListPostResponse, *response.Pagination, error)
+	ListByUser(ctx context.Context, userID string, limit int, offset int) ([]PostResponse, *response.Pagination, error)
+}
+
+// feedCommentLimit is the number of most recent comments returned with each post of the feed.
+const feedCommentLimit = 5
+
+// postCursor is the keyset position of the last post in a feed page.
+type postCursor struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ID        string    `json:"id"`
 }
 
 type dbRepository struct {
@@ -29,6 +44,7 @@ func NewRepository(db *db.DB) Repository {
 	return &dbRepository{db: db}
 }
 
+// Create stores the post and fills its ID.
 func (d *dbRepository) Create(ctx context.Context, post *Posts) error {
 	id, err := gonanoid.Generate("abcdef1234567890", 16)
 	if err != nil {
@@ -37,50 +53,113 @@ func (d *dbRepository) Create(ctx context.Context, post *Posts) error {
 
 	_, err = d.db.DB().ExecContext(ctx, `
 			INSERT INTO posts (
-				id, user_id, content, tags
+				id, user_id, content, content_text, tags, image_url
 			) VALUES (
-				$1, $2, $3, $4
+				$1, $2, $3, $4, $5, $6
 			)
-		`, id, post.UserID, post.Content, post.Tags)
+		`, id, post.UserID, post.Content, post.ContentText, post.Tags, post.ImageURL)
 	if err != nil {
 		return err
 	}
 
+	post.ID = id
 	return nil
 }
 
-// GetByID implements Repository.
+// GetByID returns the post unless it is hidden, because it was reported or its author was suspended or banned.
 func (d *dbRepository) GetByID(ctx context.Context, id string) (*Posts, error) {
 	row := d.db.DB().QueryRowContext(ctx, `
-		SELECT id, user_id, content, tags, created_at
+		SELECT posts.id, posts.user_id, posts.content, posts.content_text, posts.tags, posts.image_url, posts.created_at, posts.updated_at
 		FROM posts
-		WHERE id = $1;
+		JOIN users ON users.id = posts.user_id AND users.status = 'active'
+		WHERE posts.id = $1 AND posts.hidden_at IS NULL;
 	`, id)
 
 	p := &Posts{}
-	err := row.Scan(&p.ID, &p.UserID, &p.Content, pq.Array(&p.Tags), &p.CreatedAt)
+	err := row.Scan(&p.ID, &p.UserID, &p.Content, &p.ContentText, pq.Array(&p.Tags), &p.ImageURL, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return p, nil
 }
 
-func (d *dbRepository) CreateComment(ctx context.Context, comment *Comment) error {
-	row := d.db.DB().QueryRowContext(ctx, `
-			INSERT INTO comments (
-				user_id, post_id, content
-			) VALUES (
-				$1, $2, $3
-			)
-			RETURNING id
-		`, comment.UserID, comment.PostID, comment.Content)
-	var id uint64
-	err := row.Scan(&id)
+// Update keeps the current content and tags as a revision before overwriting them.
+func (d *dbRepository) Update(ctx context.Context, post *Posts) error {
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+				INSERT INTO post_revisions (
+					post_id, content, tags, created_at
+				)
+				SELECT id, content, tags, COALESCE(updated_at, created_at)
+				FROM posts
+				WHERE id = $1
+			`, post.ID)
+		if err != nil {
+			return err
+		}
+
+		row := tx.QueryRowContext(ctx, `
+				UPDATE posts
+				SET content = $1,
+				content_text = $2,
+				tags = $3,
+				image_url = $4,
+				updated_at = current_timestamp
+				WHERE id = $5
+				RETURNING updated_at
+			`, post.Content, post.ContentText, post.Tags, post.ImageURL, post.ID)
+		return row.Scan(&post.UpdatedAt)
+	})
+
+	return err
+}
+
+// UpdateImage sets the image of the post without recording a revision.
+func (d *dbRepository) UpdateImage(ctx context.Context, id string, imageURL *string) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		UPDATE posts
+		SET image_url = $1
+		WHERE id = $2;
+	`, imageURL, id)
+	return err
+}
+
+// Delete implements Repository.
+func (d *dbRepository) Delete(ctx context.Context, id string) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		DELETE FROM posts
+		WHERE id = $1;
+	`, id)
+	return err
+}
+
+// ListRevisions implements Repository.
+func (d *dbRepository) ListRevisions(ctx context.Context, postID string) ([]RevisionResponse, error) {
+	rows, err := d.db.DB().QueryContext(ctx, `
+		SELECT content, tags, created_at
+		FROM post_revisions
+		WHERE post_id = $1
+		ORDER BY created_at desc, id desc;
+	`, postID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	comment.ID = id
-	return nil
+	defer rows.Close()
+
+	revisions := []RevisionResponse{}
+	for rows.Next() {
+		var r RevisionResponse
+		if err := rows.Scan(&r.Content, pq.Array(&r.Tags), &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
 }
 
 func (d *dbRepository) List(ctx context.Context, filter ListPostPayload) ([]ListPostResponse, *response.Pagination, error) {
@@ -106,21 +185,34 @@ func (d *dbRepository) List(ctx context.Context, filter ListPostPayload) ([]List
 		Offset: filter.Offset,
 	}
 
+	// counting every matching post is what makes deep pages slow, cursor mode skips it
+	countStatement := "COUNT(*) OVER()"
+	if filter.Cursor != "" {
+		countStatement = "0"
+	}
+
 	withStatement = fmt.Sprintf(`
 		WITH p AS (
-			SELECT COUNT(*) OVER() AS total_count, posts.*
+			SELECT %s AS total_count, posts.*
 			FROM posts
 			LEFT JOIN user_friends uf ON uf.user_id = $%d
 			AND posts.user_id = uf.friend_id
-			WHERE (posts.user_id = $%d OR posts.user_id = uf.friend_id)
-	`, columnCtr, columnCtr+1)
+			JOIN users author ON author.id = posts.user_id AND author.status = 'active'
+			WHERE (posts.user_id = $%d OR posts.user_id = uf.friend_id) AND posts.hidden_at IS NULL
+	`, countStatement, columnCtr, columnCtr+1)
 	args = append(args, filter.UserID)
 	columnCtr++
 	args = append(args, filter.UserID)
 	columnCtr++
 
+	if filter.PostID != "" {
+		withStatement = fmt.Sprintf("%s AND posts.id = $%d", withStatement, columnCtr)
+		args = append(args, filter.PostID)
+		columnCtr++
+	}
+
 	if filter.Search != "" {
-		withStatement = fmt.Sprintf("%s AND lower(posts.content) LIKE CONCAT('%%',$%d::text,'%%')", withStatement, columnCtr)
+		withStatement = fmt.Sprintf("%s AND lower(posts.content_text) LIKE CONCAT('%%',$%d::text,'%%')", withStatement, columnCtr)
 		args = append(args, strings.ToLower(filter.Search))
 		columnCtr++
 	}
@@ -133,25 +225,56 @@ func (d *dbRepository) List(ctx context.Context, filter ListPostPayload) ([]List
 		}
 	}
 
-	withStatement = fmt.Sprintf("%s LIMIT $%d OFFSET $%d) ", withStatement, columnCtr, columnCtr+1)
+	if filter.Cursor != "" {
+		var position postCursor
+		if err := cursor.Decode(filter.Cursor, &position); err != nil {
+			return nil, nil, err
+		}
+		withStatement = fmt.Sprintf("%s AND (posts.created_at, posts.id) < ($%d, $%d)", withStatement, columnCtr, columnCtr+1)
+		args = append(args, position.CreatedAt, position.ID)
+		columnCtr += 2
+		filter.Offset = 0
+		pagination.Offset = 0
+		pagination.Cursor = filter.Cursor
+	}
 
-	args = append(args, filter.Limit)
+	// fetch one more post than requested to know whether there is a next page
+	withStatement = fmt.Sprintf("%s ORDER BY posts.created_at desc, posts.id desc LIMIT $%d OFFSET $%d) ", withStatement, columnCtr, columnCtr+1)
+
+	args = append(args, filter.Limit+1)
 	columnCtr++
 	args = append(args, filter.Offset)
 	columnCtr++
 
-	selectStatement = `
-		SELECT p.total_count, p.id as postId, p."content" as postInHtml, p.tags, p.created_at as product_created_at,
-			c.id, c."content" as "comment", c.created_at as comment_created_at,
+	// only the most recent top-level comments of each post are returned, the rest and their replies are paged
+	// through the comments endpoints
+	selectStatement = fmt.Sprintf(`
+		SELECT p.total_count, p.id as postId, p."content" as postInHtml, p.tags, p.image_url, p.created_at as product_created_at, p.updated_at,
+			cc.comment_count,
+			c.id, c."content" as "comment", c.created_at as comment_created_at, c.updated_at as comment_updated_at,
+			c.reply_count,
 			pu.id as userId, pu.name as name, pu.image_url as imageUrl, pu.friend_count as friendCount,
 			pu.created_at as user_created_at,
 			cu.id as userId, cu.name as name, cu.image_url as imageUrl, cu.friend_count as friendCount
 		FROM p
 		JOIN users pu ON pu.id = p.user_id
-		LEFT JOIN "comments" c ON p.id = c.post_id
-		LEFT JOIN users cu ON cu.id = c.user_id 
-		ORDER BY p.created_at desc, c.created_at desc
-	`
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS comment_count
+			FROM "comments"
+			WHERE "comments".post_id = p.id AND "comments".parent_id IS NULL AND "comments".hidden_at IS NULL
+		) cc ON true
+		LEFT JOIN LATERAL (
+			SELECT *, (SELECT COUNT(*) FROM "comments" r WHERE r.parent_id = "comments".id AND r.hidden_at IS NULL) AS reply_count
+			FROM "comments"
+			WHERE "comments".post_id = p.id AND "comments".parent_id IS NULL AND "comments".hidden_at IS NULL
+			ORDER BY "comments".created_at desc, "comments".id desc
+			LIMIT $%d
+		) c ON true
+		LEFT JOIN users cu ON cu.id = c.user_id
+		ORDER BY p.created_at desc, p.id desc, c.created_at desc, c.id desc
+	`, columnCtr)
+	args = append(args, feedCommentLimit)
+	columnCtr++
 
 	query = fmt.Sprintf("%s %s;", withStatement, selectStatement)
 
@@ -164,33 +287,41 @@ func (d *dbRepository) List(ctx context.Context, filter ListPostPayload) ([]List
 	resp = append(resp, ListPostResponse{})
 	for rows.Next() {
 		var p PostResponse
+		var commentCount int
+		var replyCount sql.NullInt64
 		var c comments.CommentResponse
 		var pu user.UserGetResponse
 		var cu user.UserCommentResponse
-		if err := rows.Scan(&pagination.Total, &p.ID, &p.Content, pq.Array(&p.Tags), &p.CreatedAt,
-			&c.ID, &c.Content, &c.CreatedAt,
+		if err := rows.Scan(&pagination.Total, &p.ID, &p.Content, pq.Array(&p.Tags), &p.ImageURL, &p.CreatedAt, &p.UpdatedAt,
+			&commentCount,
+			&c.ID, &c.Content, &c.CreatedAt, &c.UpdatedAt, &replyCount,
 			&pu.ID, &pu.Name, &pu.ImageURL, &pu.FriendCount, &pu.CreatedAt,
 			&cu.ID, &cu.Name, &cu.ImageURL, &cu.FriendCount); err != nil {
 			return resp, nil, err
 		}
 
+		p.Edited = p.UpdatedAt != nil
+
 		if resp[ctrIndex].PostID == "" {
 			resp[ctrIndex].PostID = p.ID
 			resp[ctrIndex].Post = p
 			resp[ctrIndex].User = pu
 			resp[ctrIndex].Comments = []comments.CommentResponse{}
+			resp[ctrIndex].CommentCount = commentCount
 		} else if resp[ctrIndex].PostID != p.ID {
 			ctrIndex++
 			resp = append(resp, ListPostResponse{
-				PostID:   p.ID,
-				Post:     p,
-				User:     pu,
-				Comments: []comments.CommentResponse{},
+				PostID:       p.ID,
+				Post:         p,
+				User:         pu,
+				Comments:     []comments.CommentResponse{},
+				CommentCount: commentCount,
 			})
 		}
 
 		if c.ID != nil {
 			c.User = cu
+			c.ReplyCount = int(replyCount.Int64)
 			resp[ctrIndex].Comments = append(resp[ctrIndex].Comments, c)
 		}
 	}
@@ -201,5 +332,53 @@ func (d *dbRepository) List(ctx context.Context, filter ListPostPayload) ([]List
 		return resp, nil, err
 	}
 
+	if len(resp) > filter.Limit {
+		resp = resp[:filter.Limit]
+		last := resp[len(resp)-1]
+		pagination.NextCursor, err = cursor.Encode(postCursor{CreatedAt: last.Post.CreatedAt, ID: last.PostID})
+		if err != nil {
+			return resp, nil, err
+		}
+	}
+
 	return resp, pagination, nil
 }
+
+// ListByUser returns every post of the user, including hidden ones, for moderation.
+func (d *dbRepository) ListByUser(ctx context.Context, userID string, limit int, offset int) ([]PostResponse, *response.Pagination, error) {
+	if limit == 0 {
+		limit = 5
+	}
+	pagination := &response.Pagination{
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	rows, err := d.db.DB().QueryContext(ctx, `
+		SELECT COUNT(*) OVER() AS total_count, id, content, tags, image_url, created_at, updated_at
+		FROM posts
+		WHERE user_id = $1
+		ORDER BY created_at desc, id desc
+		LIMIT $2 OFFSET $3;
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostResponse{}
+	for rows.Next() {
+		var p PostResponse
+		if err := rows.Scan(&pagination.Total, &p.ID, &p.Content, pq.Array(&p.Tags), &p.ImageURL, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return posts, nil, err
+		}
+		p.Edited = p.UpdatedAt != nil
+		posts = append(posts, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return posts, nil, err
+	}
+
+	return posts, pagination, nil
+}
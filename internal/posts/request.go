@@ -1,41 +1,106 @@
 package posts
 
 import (
+	"github.com/citadel-corp/segokuning-social-app/internal/common/sanitize"
+	"github.com/citadel-corp/segokuning-social-app/internal/reactions"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
 )
 
+// maxPostHTMLLength caps the raw markup, the content length itself is checked on its sanitized text.
+const maxPostHTMLLength = 5000
+
+var postTextLengthRule = validation.By(func(value interface{}) error {
+	s, _ := value.(string)
+	return validation.Validate(sanitize.Text(sanitize.HTML(s)), validation.Required, validation.Length(2, 500))
+})
+
+// CreatePostPayload.ImageID is the ID of an image uploaded through the image endpoint, attached to the post.
 type CreatePostPayload struct {
 	UserID     string
 	PostInHTML string   `json:"postInHtml"`
 	Tags       []string `json:"tags"`
+	ImageID    string   `json:"imageId"`
 }
 
 func (p CreatePostPayload) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.UserID, validation.Required.Error(ErrorUnauthorized.Message)),
-		validation.Field(&p.PostInHTML, validation.Required, validation.Length(2, 500)),
+		validation.Field(&p.PostInHTML, validation.Required, validation.Length(2, maxPostHTMLLength), postTextLengthRule),
 		validation.Field(&p.Tags, validation.Required, validation.Each(validation.NotNil, validation.Required)),
+		validation.Field(&p.ImageID, is.UUID),
+	)
+}
+
+type GetPostPayload struct {
+	UserID string
+	PostID string
+}
+
+func (p GetPostPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UserID, validation.Required.Error(ErrorUnauthorized.Message)),
+		validation.Field(&p.PostID, validation.Required),
 	)
 }
 
-type CreatePostCommentPayload struct {
-	UserID  string
-	PostID  string `json:"postId"`
-	Comment string `json:"comment"`
+// UpdatePostPayload replaces the content, the tags and the image of the post, an empty ImageID removes its image.
+type UpdatePostPayload struct {
+	UserID     string
+	PostID     string
+	PostInHTML string   `json:"postInHtml"`
+	Tags       []string `json:"tags"`
+	ImageID    string   `json:"imageId"`
 }
 
-func (p CreatePostCommentPayload) Validate() error {
+func (p UpdatePostPayload) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.UserID, validation.Required.Error(ErrorUnauthorized.Message)),
 		validation.Field(&p.PostID, validation.Required),
-		validation.Field(&p.Comment, validation.Required, validation.Length(2, 500)),
+		validation.Field(&p.PostInHTML, validation.Required, validation.Length(2, maxPostHTMLLength), postTextLengthRule),
+		validation.Field(&p.Tags, validation.Required, validation.Each(validation.NotNil, validation.Required)),
+		validation.Field(&p.ImageID, is.UUID),
 	)
 }
 
 type ListPostPayload struct {
 	UserID     string
+	PostID     string   `schema:"-"`
 	Search     string   `schema:"search" binding:"omitempty"`
 	SearchTags []string `schema:"searchTag" binding:"omitempty"`
+	Cursor     string   `schema:"cursor" binding:"omitempty"`
+	Limit      int
+	Offset     int
+}
+
+type ReactPayload struct {
+	UserID     string
+	TargetType reactions.TargetType
+	TargetID   string
+	Kind       string `json:"kind"`
+}
+
+func (p ReactPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UserID, validation.Required.Error(ErrorUnauthorized.Message)),
+		validation.Field(&p.TargetID, validation.Required),
+		validation.Field(&p.Kind, validation.Required, validation.In(reactions.Kinds...)),
+	)
+}
+
+type ListReactionPayload struct {
+	UserID     string
+	TargetType reactions.TargetType
+	TargetID   string
+	Kind       string
 	Limit      int
 	Offset     int
 }
+
+func (p ListReactionPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UserID, validation.Required.Error(ErrorUnauthorized.Message)),
+		validation.Field(&p.TargetID, validation.Required),
+		validation.Field(&p.Kind, validation.In(reactions.Kinds...)),
+	)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/citadel-corp/segokuning-social-app/internal/comments"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/citadel-corp/segokuning-social-app/internal/reactions"
 	"github.com/citadel-corp/segokuning-social-app/internal/user"
 )
 
@@ -17,20 +18,36 @@ type Response struct {
 }
 
 var (
-	SuccessCreateResponse        = Response{Code: 200, Message: "Post created successfully"}
-	SuccessCreateCommentResponse = Response{Code: 200, Message: "Comment created successfully"}
-	SuccessListResponse          = Response{Code: 200, Message: "Posts fetched successfully"}
+	SuccessCreateResponse       = Response{Code: 200, Message: "Post created successfully"}
+	SuccessListResponse         = Response{Code: 200, Message: "Posts fetched successfully"}
+	SuccessGetResponse          = Response{Code: 200, Message: "Post fetched successfully"}
+	SuccessUpdateResponse       = Response{Code: 200, Message: "Post updated successfully"}
+	SuccessDeleteResponse       = Response{Code: 200, Message: "Post deleted successfully"}
+	SuccessListRevisionResponse = Response{Code: 200, Message: "Post revisions fetched successfully"}
+	SuccessReactResponse        = Response{Code: 200, Message: "Reaction updated successfully"}
+	SuccessListReactionResponse = Response{Code: 200, Message: "Reactions fetched successfully"}
 )
 
 type ListPostResponse struct {
-	PostID   string                     `json:"postId"`
-	Post     PostResponse               `json:"post"`
-	Comments []comments.CommentResponse `json:"comments"`
-	User     user.UserGetResponse       `json:"creator"`
+	PostID       string                     `json:"postId"`
+	Post         PostResponse               `json:"post"`
+	Comments     []comments.CommentResponse `json:"comments"`
+	CommentCount int                        `json:"commentCount"`
+	User         user.UserGetResponse       `json:"creator"`
+	Reactions    reactions.Summary          `json:"reactions"`
 }
 
 type PostResponse struct {
-	ID        string    `json:"-"`
+	ID        string     `json:"-"`
+	Content   string     `json:"postInHtml"`
+	Tags      []string   `json:"tags"`
+	ImageURL  *string    `json:"imageUrl,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	Edited    bool       `json:"edited"`
+}
+
+type RevisionResponse struct {
 	Content   string    `json:"postInHtml"`
 	Tags      []string  `json:"tags"`
 	CreatedAt time.Time `json:"createdAt"`
@@ -4,32 +4,55 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strconv"
 
+	"github.com/citadel-corp/segokuning-social-app/internal/comments"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/cursor"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/sanitize"
+	"github.com/citadel-corp/segokuning-social-app/internal/image"
+	"github.com/citadel-corp/segokuning-social-app/internal/reactions"
 	userfriends "github.com/citadel-corp/segokuning-social-app/internal/user_friends"
 )
 
 type Service interface {
 	Create(ctx context.Context, req CreatePostPayload) Response
-	CreatePostComment(ctx context.Context, req CreatePostCommentPayload) Response
 	List(ctx context.Context, req ListPostPayload) Response
+	Get(ctx context.Context, req GetPostPayload) Response
+	Update(ctx context.Context, req UpdatePostPayload) Response
+	Delete(ctx context.Context, req GetPostPayload) Response
+	ListRevisions(ctx context.Context, req GetPostPayload) Response
+	React(ctx context.Context, req ReactPayload) Response
+	ListReactions(ctx context.Context, req ListReactionPayload) Response
 }
 
 type postsService struct {
 	repository            Repository
 	userFriendsRepository userfriends.Repository
+	reactionsRepository   reactions.Repository
+	commentsRepository    comments.Repository
+	imageService          image.Service
 }
 
-func NewService(repository Repository, userFriendsRepository userfriends.Repository) Service {
-	return &postsService{repository: repository, userFriendsRepository: userFriendsRepository}
+func NewService(repository Repository, userFriendsRepository userfriends.Repository, reactionsRepository reactions.Repository,
+	commentsRepository comments.Repository, imageService image.Service) Service {
+	return &postsService{
+		repository:            repository,
+		userFriendsRepository: userFriendsRepository,
+		reactionsRepository:   reactionsRepository,
+		commentsRepository:    commentsRepository,
+		imageService:          imageService,
+	}
 }
 
 func (s *postsService) Create(ctx context.Context, req CreatePostPayload) Response {
 	var resp Response
 
+	content := sanitize.HTML(req.PostInHTML)
 	post := &Posts{
-		UserID:  req.UserID,
-		Content: req.PostInHTML,
-		Tags:    req.Tags,
+		UserID:      req.UserID,
+		Content:     content,
+		ContentText: sanitize.Text(content),
+		Tags:        req.Tags,
 	}
 
 	err := s.repository.Create(ctx, post)
@@ -39,29 +62,121 @@ func (s *postsService) Create(ctx context.Context, req CreatePostPayload) Respon
 		return resp
 	}
 
+	// the image is referenced by the post ID, which is only known once the post is stored
+	if req.ImageID != "" {
+		imageURL, errResp := s.referenceImage(ctx, req.UserID, post.ID, req.ImageID)
+		if errResp == nil {
+			err = s.repository.UpdateImage(ctx, post.ID, imageURL)
+			if err != nil {
+				resp = ErrorInternal
+				resp.Error = err.Error()
+				errResp = &resp
+			}
+		}
+		if errResp != nil {
+			// the post is not kept without the image it was created with
+			_ = s.repository.Delete(ctx, post.ID)
+			_ = s.imageService.ReleaseReference(ctx, image.PostReference(post.ID))
+			return *errResp
+		}
+	}
+
 	return SuccessCreateResponse
 }
 
-func (s *postsService) CreatePostComment(ctx context.Context, req CreatePostCommentPayload) Response {
+func (s *postsService) List(ctx context.Context, req ListPostPayload) Response {
 	var resp Response
 
-	//validate post is not found
-	post, err := s.repository.GetByID(ctx, req.PostID)
-	if errors.Is(err, sql.ErrNoRows) {
-		return ErrorNotFound
+	posts, pagination, err := s.repository.List(ctx, req)
+	if errors.Is(err, cursor.ErrInvalidCursor) {
+		resp = ErrorBadRequest
+		resp.Error = err.Error()
+		return resp
 	}
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			resp = ErrorInternal
+			resp.Error = err.Error()
+			return resp
+		}
+	}
+
+	err = s.attachReactions(ctx, req.UserID, posts)
 	if err != nil {
 		resp = ErrorInternal
 		resp.Error = err.Error()
 		return resp
 	}
 
-	//validate post creator is users friend
-	if post.UserID != req.UserID {
-		_, err = s.userFriendsRepository.GetByFriendID(ctx, req.UserID, post.UserID)
+	resp = SuccessListResponse
+	resp.Data = posts
+	resp.Meta = pagination
+
+	return resp
+}
+
+func (s *postsService) Get(ctx context.Context, req GetPostPayload) Response {
+	var resp Response
+
+	// the feed query only returns posts of the user and their friends
+	posts, _, err := s.repository.List(ctx, ListPostPayload{
+		UserID: req.UserID,
+		PostID: req.PostID,
+		Limit:  1,
+	})
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return ErrorBadRequest
+			return ErrorNotFound
+		}
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+	if len(posts) == 0 || posts[0].PostID == "" {
+		return ErrorNotFound
+	}
+
+	err = s.attachReactions(ctx, req.UserID, posts)
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp = SuccessGetResponse
+	resp.Data = posts[0]
+
+	return resp
+}
+
+func (s *postsService) Update(ctx context.Context, req UpdatePostPayload) Response {
+	var resp Response
+
+	post, errResp := s.getOwnPost(ctx, req.UserID, req.PostID)
+	if errResp != nil {
+		return *errResp
+	}
+
+	post.Content = sanitize.HTML(req.PostInHTML)
+	post.ContentText = sanitize.Text(post.Content)
+	post.Tags = req.Tags
+	post.ImageURL = nil
+	if req.ImageID != "" {
+		post.ImageURL, errResp = s.referenceImage(ctx, req.UserID, post.ID, req.ImageID)
+		if errResp != nil {
+			return *errResp
 		}
+	}
+
+	err := s.repository.Update(ctx, post)
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	if req.ImageID == "" {
+		err = s.imageService.ReleaseReference(ctx, image.PostReference(post.ID))
 		if err != nil {
 			resp = ErrorInternal
 			resp.Error = err.Error()
@@ -69,37 +184,250 @@ func (s *postsService) CreatePostComment(ctx context.Context, req CreatePostComm
 		}
 	}
 
-	comment := &Comment{
-		UserID:  req.UserID,
-		PostID:  req.PostID,
-		Content: req.Comment,
+	return SuccessUpdateResponse
+}
+
+func (s *postsService) Delete(ctx context.Context, req GetPostPayload) Response {
+	var resp Response
+
+	post, errResp := s.getOwnPost(ctx, req.UserID, req.PostID)
+	if errResp != nil {
+		return *errResp
 	}
 
-	err = s.repository.CreateComment(ctx, comment)
+	err := s.repository.Delete(ctx, post.ID)
 	if err != nil {
 		resp = ErrorInternal
 		resp.Error = err.Error()
 		return resp
 	}
 
-	return SuccessCreateCommentResponse
+	err = s.imageService.ReleaseReference(ctx, image.PostReference(post.ID))
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	return SuccessDeleteResponse
 }
 
-func (s *postsService) List(ctx context.Context, req ListPostPayload) Response {
+func (s *postsService) ListRevisions(ctx context.Context, req GetPostPayload) Response {
 	var resp Response
 
-	posts, pagination, err := s.repository.List(ctx, req)
+	post, errResp := s.getVisiblePost(ctx, req.UserID, req.PostID)
+	if errResp != nil {
+		return *errResp
+	}
+
+	revisions, err := s.repository.ListRevisions(ctx, post.ID)
 	if err != nil {
-		if !errors.Is(err, sql.ErrNoRows) {
-			resp = ErrorInternal
-			resp.Error = err.Error()
-			return resp
-		}
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
 	}
 
-	resp = SuccessListResponse
-	resp.Data = posts
+	resp = SuccessListRevisionResponse
+	resp.Data = revisions
+
+	return resp
+}
+
+func (s *postsService) React(ctx context.Context, req ReactPayload) Response {
+	var resp Response
+
+	errResp := s.checkReactionTarget(ctx, req.UserID, req.TargetType, req.TargetID)
+	if errResp != nil {
+		return *errResp
+	}
+
+	_, err := s.reactionsRepository.Toggle(ctx, &reactions.Reaction{
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		UserID:     req.UserID,
+		Kind:       reactions.Kind(req.Kind),
+	})
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	summaries, err := s.reactionsRepository.Summarize(ctx, req.TargetType, []string{req.TargetID}, req.UserID)
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp = SuccessReactResponse
+	resp.Data = summaries[req.TargetID]
+
+	return resp
+}
+
+func (s *postsService) ListReactions(ctx context.Context, req ListReactionPayload) Response {
+	var resp Response
+
+	errResp := s.checkReactionTarget(ctx, req.UserID, req.TargetType, req.TargetID)
+	if errResp != nil {
+		return *errResp
+	}
+
+	reactors, pagination, err := s.reactionsRepository.ListReactors(ctx, reactions.ListReactorPayload{
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Kind:       req.Kind,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+	})
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp = SuccessListReactionResponse
+	resp.Data = reactors
 	resp.Meta = pagination
 
 	return resp
 }
+
+// checkReactionTarget makes sure the reacted post, or the post of the reacted comment, is visible to the user.
+func (s *postsService) checkReactionTarget(ctx context.Context, userID string, targetType reactions.TargetType, targetID string) *Response {
+	postID := targetID
+	if targetType == reactions.TargetComment {
+		commentID, err := strconv.ParseUint(targetID, 10, 64)
+		if err != nil {
+			return &ErrorNotFound
+		}
+		comment, err := s.commentsRepository.GetByID(ctx, commentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return &ErrorNotFound
+		}
+		if err != nil {
+			resp := ErrorInternal
+			resp.Error = err.Error()
+			return &resp
+		}
+		postID = comment.PostID
+	}
+
+	_, errResp := s.getVisiblePost(ctx, userID, postID)
+	return errResp
+}
+
+// attachReactions fills the reaction summaries of the listed posts and their comments.
+func (s *postsService) attachReactions(ctx context.Context, userID string, posts []ListPostResponse) error {
+	var postIDs, commentIDs []string
+	for _, p := range posts {
+		if p.PostID == "" {
+			continue
+		}
+		postIDs = append(postIDs, p.PostID)
+		for _, c := range p.Comments {
+			if c.ID != nil {
+				commentIDs = append(commentIDs, *c.ID)
+			}
+		}
+	}
+
+	postSummaries, err := s.reactionsRepository.Summarize(ctx, reactions.TargetPost, postIDs, userID)
+	if err != nil {
+		return err
+	}
+	commentSummaries, err := s.reactionsRepository.Summarize(ctx, reactions.TargetComment, commentIDs, userID)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Reactions = postSummaries[posts[i].PostID]
+		for j := range posts[i].Comments {
+			if id := posts[i].Comments[j].ID; id != nil {
+				posts[i].Comments[j].Reactions = commentSummaries[*id]
+			}
+		}
+	}
+
+	return nil
+}
+
+// referenceImage attaches the image of the user to the post, releasing the image the post used before, and
+// returns the URL of the image.
+func (s *postsService) referenceImage(ctx context.Context, userID string, postID string, imageID string) (*string, *Response) {
+	imageURL, err := s.imageService.Reference(ctx, imageID, userID, image.PostReference(postID))
+	if errors.Is(err, image.ErrImageNotFound) || errors.Is(err, image.ErrImageInUse) {
+		resp := ErrorBadRequest
+		resp.Error = "imageId: " + err.Error()
+		return nil, &resp
+	}
+	if err != nil {
+		resp := ErrorInternal
+		resp.Error = err.Error()
+		return nil, &resp
+	}
+	return &imageURL, nil
+}
+
+// getOwnPost returns the post only when it was created by the user.
+func (s *postsService) getOwnPost(ctx context.Context, userID string, postID string) (*Posts, *Response) {
+	post, err := s.repository.GetByID(ctx, postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &ErrorNotFound
+	}
+	if err != nil {
+		resp := ErrorInternal
+		resp.Error = err.Error()
+		return nil, &resp
+	}
+
+	if post.UserID != userID {
+		return nil, &ErrorForbidden
+	}
+
+	return post, nil
+}
+
+// getVisiblePost returns the post only when it can be seen by the user.
+func (s *postsService) getVisiblePost(ctx context.Context, userID string, postID string) (*Posts, *Response) {
+	post, err := s.repository.GetByID(ctx, postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &ErrorNotFound
+	}
+	if err != nil {
+		resp := ErrorInternal
+		resp.Error = err.Error()
+		return nil, &resp
+	}
+
+	visible, err := s.isVisible(ctx, userID, post)
+	if err != nil {
+		resp := ErrorInternal
+		resp.Error = err.Error()
+		return nil, &resp
+	}
+	if !visible {
+		return nil, &ErrorNotFound
+	}
+
+	return post, nil
+}
+
+// isVisible reports whether the post was created by the user or one of their friends.
+func (s *postsService) isVisible(ctx context.Context, userID string, post *Posts) (bool, error) {
+	if post.UserID == userID {
+		return true, nil
+	}
+
+	_, err := s.userFriendsRepository.GetByFriendID(ctx, userID, post.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,33 @@
+package reactions
+
+import "time"
+
+type Kind string
+
+var (
+	KindLike  Kind = "like"
+	KindLove  Kind = "love"
+	KindHaha  Kind = "haha"
+	KindWow   Kind = "wow"
+	KindSad   Kind = "sad"
+	KindAngry Kind = "angry"
+)
+
+var Kinds []interface{} = []interface{}{
+	string(KindLike), string(KindLove), string(KindHaha), string(KindWow), string(KindSad), string(KindAngry),
+}
+
+type TargetType string
+
+var (
+	TargetPost    TargetType = "post"
+	TargetComment TargetType = "comment"
+)
+
+type Reaction struct {
+	TargetType TargetType
+	TargetID   string
+	UserID     string
+	Kind       Kind
+	CreatedAt  time.Time
+}
@@ -0,0 +1,201 @@
+package reactions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+)
+
+type Repository interface {
+	Toggle(ctx context.Context, reaction *Reaction) (bool, error)
+	Summarize(ctx context.Context, targetType TargetType, targetIDs []string, userID string) (map[string]Summary, error)
+	ListReactors(ctx context.Context, filter ListReactorPayload) ([]ReactorResponse, *response.Pagination, error)
+}
+
+type dbRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+type target struct {
+	table  string
+	column string
+	idType string
+}
+
+var targets = map[TargetType]target{
+	TargetPost:    {table: "post_reactions", column: "post_id", idType: "text"},
+	TargetComment: {table: "comment_reactions", column: "comment_id", idType: "int"},
+}
+
+// Toggle removes the user's reaction when it has the same kind, otherwise it sets the reaction to the given kind.
+// It reports whether the user still reacts to the target afterwards.
+func (d *dbRepository) Toggle(ctx context.Context, reaction *Reaction) (bool, error) {
+	t, ok := targets[reaction.TargetType]
+	if !ok {
+		return false, fmt.Errorf("unknown reaction target %q", reaction.TargetType)
+	}
+
+	var reacted bool
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		var kind Kind
+		row := tx.QueryRowContext(ctx, fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE %s = CAST($1::text AS %s) AND user_id = $2 AND kind = $3
+				RETURNING kind
+			`, t.table, t.column, t.idType), reaction.TargetID, reaction.UserID, reaction.Kind)
+		err := row.Scan(&kind)
+		if err == nil {
+			reacted = false
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+				INSERT INTO %s (
+					%s, user_id, kind
+				) VALUES (
+					CAST($1::text AS %s), $2, $3
+				)
+				ON CONFLICT (%s, user_id) DO UPDATE
+				SET kind = EXCLUDED.kind, created_at = current_timestamp
+			`, t.table, t.column, t.idType, t.column), reaction.TargetID, reaction.UserID, reaction.Kind)
+		if err != nil {
+			return err
+		}
+		reacted = true
+		return nil
+	})
+
+	return reacted, err
+}
+
+// Summarize returns per-kind reaction counts for every target, keyed by target ID.
+func (d *dbRepository) Summarize(ctx context.Context, targetType TargetType, targetIDs []string, userID string) (map[string]Summary, error) {
+	t, ok := targets[targetType]
+	if !ok {
+		return nil, fmt.Errorf("unknown reaction target %q", targetType)
+	}
+
+	summaries := make(map[string]Summary, len(targetIDs))
+	for _, id := range targetIDs {
+		summaries[id] = NewSummary()
+	}
+	if len(targetIDs) == 0 {
+		return summaries, nil
+	}
+
+	rows, err := d.db.DB().QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s::text, kind, COUNT(*), BOOL_OR(user_id = $2)
+		FROM %s
+		WHERE %s = ANY(CAST($1::text[] AS %s[]))
+		GROUP BY %s, kind;
+	`, t.column, t.table, t.column, t.idType, t.column), targetIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id      string
+			kind    Kind
+			count   int
+			reacted bool
+		)
+		if err := rows.Scan(&id, &kind, &count, &reacted); err != nil {
+			return nil, err
+		}
+		summary, ok := summaries[id]
+		if !ok {
+			summary = NewSummary()
+		}
+		summary.Counts[kind] = count
+		summary.Total += count
+		if reacted {
+			summary.ReactedByMe = true
+			summary.MyReaction = &kind
+		}
+		summaries[id] = summary
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+// ListReactors implements Repository.
+func (d *dbRepository) ListReactors(ctx context.Context, filter ListReactorPayload) ([]ReactorResponse, *response.Pagination, error) {
+	t, ok := targets[filter.TargetType]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown reaction target %q", filter.TargetType)
+	}
+
+	var (
+		whereStatement string
+		args           []interface{}
+		columnCtr      int = 1
+	)
+
+	if filter.Limit == 0 {
+		filter.Limit = 5
+	}
+
+	pagination := &response.Pagination{
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+
+	whereStatement = fmt.Sprintf("WHERE r.%s = CAST($%d::text AS %s)", t.column, columnCtr, t.idType)
+	args = append(args, filter.TargetID)
+	columnCtr++
+
+	if filter.Kind != "" {
+		whereStatement = fmt.Sprintf("%s AND r.kind = $%d", whereStatement, columnCtr)
+		args = append(args, filter.Kind)
+		columnCtr++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER() AS total_count, u.id as userId, u.name as name, u.image_url as imageUrl,
+			u.friend_count as friendCount, r.kind, r.created_at
+		FROM %s r
+		JOIN users u ON u.id = r.user_id
+		%s
+		ORDER BY r.created_at desc
+		LIMIT $%d OFFSET $%d;
+	`, t.table, whereStatement, columnCtr, columnCtr+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := d.db.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	reactors := []ReactorResponse{}
+	for rows.Next() {
+		var r ReactorResponse
+		if err := rows.Scan(&pagination.Total, &r.UserID, &r.Name, &r.ImageURL, &r.FriendCount, &r.Kind, &r.CreatedAt); err != nil {
+			return reactors, nil, err
+		}
+		reactors = append(reactors, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reactors, nil, err
+	}
+
+	return reactors, pagination, nil
+}
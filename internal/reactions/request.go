@@ -0,0 +1,9 @@
+package reactions
+
+type ListReactorPayload struct {
+	TargetType TargetType
+	TargetID   string
+	Kind       string
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,23 @@
+package reactions
+
+import "time"
+
+type Summary struct {
+	Counts      map[Kind]int `json:"counts"`
+	Total       int          `json:"total"`
+	ReactedByMe bool         `json:"reactedByMe"`
+	MyReaction  *Kind        `json:"myReaction"`
+}
+
+func NewSummary() Summary {
+	return Summary{Counts: map[Kind]int{}}
+}
+
+type ReactorResponse struct {
+	UserID      string    `json:"userId"`
+	Name        string    `json:"name"`
+	ImageURL    *string   `json:"imageUrl"`
+	FriendCount int       `json:"friendCount"`
+	Kind        Kind      `json:"kind"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
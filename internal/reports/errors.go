@@ -0,0 +1,12 @@
+package reports
+
+import "errors"
+
+var (
+	ErrValidationFailed   = errors.New("validation failed")
+	ErrTargetNotFound     = errors.New("reported content not found")
+	ErrCannotReportSelf   = errors.New("cannot report your own content")
+	ErrAlreadyReported    = errors.New("already reported")
+	ErrCaseNotFound       = errors.New("report not found")
+	ErrCaseAlreadyDecided = errors.New("report already decided")
+)
@@ -0,0 +1,99 @@
+package reports
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/request"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) ReportPost(w http.ResponseWriter, r *http.Request) {
+	h.report(w, r, TargetPost, mux.Vars(r)["postId"])
+}
+
+func (h *Handler) ReportComment(w http.ResponseWriter, r *http.Request) {
+	h.report(w, r, TargetComment, mux.Vars(r)["commentId"])
+}
+
+func (h *Handler) ReportUser(w http.ResponseWriter, r *http.Request) {
+	h.report(w, r, TargetUser, mux.Vars(r)["userId"])
+}
+
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, targetType TargetType, targetID string) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var req ReportPayload
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	req.TargetType = targetType
+	req.TargetID = targetID
+	req.ReporterID = userID
+
+	err = h.service.Report(r.Context(), req)
+	if errors.Is(err, ErrValidationFailed) || errors.Is(err, ErrCannotReportSelf) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrTargetNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrAlreadyReported) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Reported successfully",
+	})
+}
+
+func getUserID(r *http.Request) (string, error) {
+	if principal, ok := middleware.PrincipalFromContext(r.Context()); ok {
+		return principal.UserID, nil
+	}
+	slog.Error("cannot parse auth value from context")
+	return "", errors.New("cannot parse auth value from context")
+}
@@ -0,0 +1,67 @@
+package reports
+
+import "time"
+
+type TargetType string
+
+var (
+	TargetPost    TargetType = "post"
+	TargetComment TargetType = "comment"
+	TargetUser    TargetType = "user"
+)
+
+var TargetTypes []interface{} = []interface{}{
+	TargetPost, TargetComment, TargetUser,
+}
+
+type Reason string
+
+var (
+	ReasonSpam           Reason = "spam"
+	ReasonHarassment     Reason = "harassment"
+	ReasonHateSpeech     Reason = "hate_speech"
+	ReasonViolence       Reason = "violence"
+	ReasonNudity         Reason = "nudity"
+	ReasonMisinformation Reason = "misinformation"
+	ReasonOther          Reason = "other"
+)
+
+var Reasons []interface{} = []interface{}{
+	ReasonSpam, ReasonHarassment, ReasonHateSpeech, ReasonViolence, ReasonNudity, ReasonMisinformation, ReasonOther,
+}
+
+type Status string
+
+var (
+	StatusOpen      Status = "open"
+	StatusDismissed Status = "dismissed"
+	StatusTakenDown Status = "taken_down"
+)
+
+var Statuses []interface{} = []interface{}{
+	StatusOpen, StatusDismissed, StatusTakenDown,
+}
+
+// Case groups the reports about one post, comment or user until an admin decides on it.
+type Case struct {
+	ID           string
+	TargetType   TargetType
+	TargetID     string
+	TargetUserID string
+	Status       Status
+	ReportCount  int
+	HiddenAt     *time.Time
+	DecidedBy    *string
+	DecisionNote string
+	DecidedAt    *time.Time
+	CreatedAt    time.Time
+}
+
+type Report struct {
+	ID         string
+	CaseID     string
+	ReporterID string
+	Reason     Reason
+	Detail     string
+	CreatedAt  time.Time
+}
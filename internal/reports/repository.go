@@ -0,0 +1,370 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+)
+
+type Repository interface {
+	Create(ctx context.Context, c *Case, report *Report, hideThreshold int) error
+	GetCase(ctx context.Context, id string) (*Case, error)
+	ListCases(ctx context.Context, filter ListCasePayload) ([]CaseResponse, *response.Pagination, error)
+	ListReports(ctx context.Context, caseID string) ([]ReportResponse, error)
+	Decide(ctx context.Context, c *Case) error
+}
+
+type dbRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+type target struct {
+	table       string
+	idType      string
+	ownerColumn string
+	hideable    bool
+}
+
+var targets = map[TargetType]target{
+	TargetPost:    {table: "posts", idType: "text", ownerColumn: "user_id", hideable: true},
+	TargetComment: {table: "comments", idType: "bigint", ownerColumn: "user_id", hideable: true},
+	TargetUser:    {table: "users", idType: "text", ownerColumn: "id"},
+}
+
+// Create files the report under the open case of its target, opening a new case on the first report
+// and on the first one after the previous case was decided. A repeated report by the same reporter
+// on the same case returns ErrAlreadyReported and is not counted. Posts and comments
+// of an open case are hidden once it reaches hideThreshold reports, a zero threshold never hides them.
+// Hidden posts and comments are not found, so that a case dismissed later cannot show content that was taken down.
+func (d *dbRepository) Create(ctx context.Context, c *Case, report *Report, hideThreshold int) error {
+	t, ok := targets[c.TargetType]
+	if !ok {
+		return fmt.Errorf("unknown report target %q", c.TargetType)
+	}
+
+	// the content is locked until the report is filed, so that a decision taking it down waits for it
+	visibleStatement := ""
+	if t.hideable {
+		visibleStatement = "AND hidden_at IS NULL FOR SHARE"
+	}
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, fmt.Sprintf(`
+				SELECT %s
+				FROM %s
+				WHERE id = CAST($1::text AS %s) %s
+			`, t.ownerColumn, t.table, t.idType, visibleStatement), c.TargetID)
+		err := row.Scan(&c.TargetUserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTargetNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if c.TargetUserID == report.ReporterID {
+			return ErrCannotReportSelf
+		}
+
+		_, err = tx.ExecContext(ctx, `
+				INSERT INTO report_cases (
+					id, target_type, target_id, target_user_id
+				) VALUES (
+					$1, $2, $3, $4
+				)
+				ON CONFLICT (target_type, target_id) WHERE status = 'open' DO NOTHING
+			`, c.ID, c.TargetType, c.TargetID, c.TargetUserID)
+		if err != nil {
+			return err
+		}
+		row = tx.QueryRowContext(ctx, `
+				SELECT id, status, hidden_at
+				FROM report_cases
+				WHERE target_type = $1 AND target_id = $2 AND status = $3
+				FOR UPDATE
+			`, c.TargetType, c.TargetID, StatusOpen)
+		err = row.Scan(&c.ID, &c.Status, &c.HiddenAt)
+		if err != nil {
+			return err
+		}
+
+		report.CaseID = c.ID
+		res, err := tx.ExecContext(ctx, `
+				INSERT INTO reports (
+					id, case_id, reporter_id, reason, detail
+				) VALUES (
+					$1, $2, $3, $4, $5
+				)
+				ON CONFLICT (case_id, reporter_id) DO NOTHING
+			`, report.ID, report.CaseID, report.ReporterID, report.Reason, report.Detail)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrAlreadyReported
+		}
+
+		row = tx.QueryRowContext(ctx, `
+				UPDATE report_cases
+				SET report_count = report_count + 1
+				WHERE id = $1
+				RETURNING report_count
+			`, c.ID)
+		err = row.Scan(&c.ReportCount)
+		if err != nil {
+			return err
+		}
+
+		if !t.hideable || hideThreshold <= 0 || c.HiddenAt != nil || c.ReportCount < hideThreshold {
+			return nil
+		}
+		row = tx.QueryRowContext(ctx, `
+				UPDATE report_cases
+				SET hidden_at = current_timestamp
+				WHERE id = $1
+				RETURNING hidden_at
+			`, c.ID)
+		err = row.Scan(&c.HiddenAt)
+		if err != nil {
+			return err
+		}
+		return setHidden(ctx, tx, t, c.TargetID, true)
+	})
+
+	return err
+}
+
+// GetCase implements Repository.
+func (d *dbRepository) GetCase(ctx context.Context, id string) (*Case, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT id, target_type, target_id, target_user_id, status, report_count, hidden_at,
+			decided_by, decision_note, decided_at, created_at
+		FROM report_cases
+		WHERE id = $1;
+	`, id)
+
+	c := &Case{}
+	err := row.Scan(&c.ID, &c.TargetType, &c.TargetID, &c.TargetUserID, &c.Status, &c.ReportCount, &c.HiddenAt,
+		&c.DecidedBy, &c.DecisionNote, &c.DecidedAt, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCaseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ListCases returns the cases with the given status, the most reported first.
+func (d *dbRepository) ListCases(ctx context.Context, filter ListCasePayload) ([]CaseResponse, *response.Pagination, error) {
+	var (
+		whereStatement string
+		args           []interface{}
+		columnCtr      int = 1
+	)
+
+	if filter.Status == "" {
+		filter.Status = StatusOpen
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 5
+	}
+
+	pagination := &response.Pagination{
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+
+	whereStatement = fmt.Sprintf("WHERE status = $%d", columnCtr)
+	args = append(args, filter.Status)
+	columnCtr++
+
+	if filter.TargetType != "" {
+		whereStatement = fmt.Sprintf("%s AND target_type = $%d", whereStatement, columnCtr)
+		args = append(args, filter.TargetType)
+		columnCtr++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER() AS total_count, id, target_type, target_id, target_user_id, status, report_count,
+			hidden_at IS NOT NULL, decided_by, decision_note, decided_at, created_at
+		FROM report_cases
+		%s
+		ORDER BY report_count desc, created_at asc, id asc
+		LIMIT $%d OFFSET $%d;
+	`, whereStatement, columnCtr, columnCtr+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := d.db.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	cases := []CaseResponse{}
+	caseIDs := []string{}
+	for rows.Next() {
+		c := CaseResponse{Reasons: map[Reason]int{}}
+		if err := rows.Scan(&pagination.Total, &c.ID, &c.TargetType, &c.TargetID, &c.TargetUserID, &c.Status, &c.ReportCount,
+			&c.Hidden, &c.DecidedBy, &c.DecisionNote, &c.DecidedAt, &c.CreatedAt); err != nil {
+			return cases, nil, err
+		}
+		cases = append(cases, c)
+		caseIDs = append(caseIDs, c.ID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return cases, nil, err
+	}
+	if len(cases) == 0 {
+		return cases, pagination, nil
+	}
+
+	reasons, err := d.countReasons(ctx, caseIDs)
+	if err != nil {
+		return cases, nil, err
+	}
+	for i := range cases {
+		if counts, ok := reasons[cases[i].ID]; ok {
+			cases[i].Reasons = counts
+		}
+	}
+
+	return cases, pagination, nil
+}
+
+// ListReports implements Repository.
+func (d *dbRepository) ListReports(ctx context.Context, caseID string) ([]ReportResponse, error) {
+	rows, err := d.db.DB().QueryContext(ctx, `
+		SELECT reporter_id, reason, detail, created_at
+		FROM reports
+		WHERE case_id = $1
+		ORDER BY created_at asc, id asc;
+	`, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []ReportResponse{}
+	for rows.Next() {
+		var r ReportResponse
+		if err := rows.Scan(&r.ReporterID, &r.Reason, &r.Detail, &r.CreatedAt); err != nil {
+			return reports, err
+		}
+		reports = append(reports, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reports, err
+	}
+
+	return reports, nil
+}
+
+// Decide records the decision of an open case. Dismissing it shows its post or comment again,
+// taking it down keeps them hidden. It returns ErrCaseAlreadyDecided when the case is no longer open.
+func (d *dbRepository) Decide(ctx context.Context, c *Case) error {
+	t, ok := targets[c.TargetType]
+	if !ok {
+		return fmt.Errorf("unknown report target %q", c.TargetType)
+	}
+
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		// the content is locked before the case, in the same order as Create
+		if t.hideable {
+			_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+					SELECT id
+					FROM %s
+					WHERE id = CAST($1::text AS %s)
+					FOR UPDATE
+				`, t.table, t.idType), c.TargetID)
+			if err != nil {
+				return err
+			}
+		}
+
+		row := tx.QueryRowContext(ctx, `
+				UPDATE report_cases
+				SET status = $1,
+				decided_by = $2,
+				decision_note = $3,
+				decided_at = current_timestamp,
+				hidden_at = CASE WHEN $4 THEN NULL ELSE COALESCE(hidden_at, current_timestamp) END
+				WHERE id = $5 AND status = $6
+				RETURNING hidden_at, decided_at
+			`, c.Status, c.DecidedBy, c.DecisionNote, c.Status == StatusDismissed, c.ID, StatusOpen)
+		err := row.Scan(&c.HiddenAt, &c.DecidedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCaseAlreadyDecided
+		}
+		if err != nil {
+			return err
+		}
+
+		if !t.hideable {
+			return nil
+		}
+		return setHidden(ctx, tx, t, c.TargetID, c.Status == StatusTakenDown)
+	})
+
+	return err
+}
+
+func (d *dbRepository) countReasons(ctx context.Context, caseIDs []string) (map[string]map[Reason]int, error) {
+	rows, err := d.db.DB().QueryContext(ctx, `
+		SELECT case_id, reason, COUNT(*)
+		FROM reports
+		WHERE case_id = ANY($1)
+		GROUP BY case_id, reason;
+	`, caseIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reasons := make(map[string]map[Reason]int, len(caseIDs))
+	for rows.Next() {
+		var (
+			caseID string
+			reason Reason
+			count  int
+		)
+		if err := rows.Scan(&caseID, &reason, &count); err != nil {
+			return nil, err
+		}
+		if _, ok := reasons[caseID]; !ok {
+			reasons[caseID] = map[Reason]int{}
+		}
+		reasons[caseID][reason] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reasons, nil
+}
+
+func setHidden(ctx context.Context, tx *sql.Tx, t target, targetID string, hidden bool) error {
+	hiddenAt := "NULL"
+	if hidden {
+		hiddenAt = "COALESCE(hidden_at, current_timestamp)"
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s
+			SET hidden_at = %s
+			WHERE id = CAST($1::text AS %s)
+		`, t.table, hiddenAt, t.idType), targetID)
+	return err
+}
@@ -0,0 +1,53 @@
+package reports
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// commentIDRule accepts the comment IDs the comments handlers parse that also fit the bigint the
+// report target lookup casts them to.
+var commentIDRule = validation.By(func(value interface{}) error {
+	s, _ := value.(string)
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || id > math.MaxInt64 {
+		return errors.New("must be a valid comment id")
+	}
+	return nil
+})
+
+type ReportPayload struct {
+	TargetType TargetType `json:"-"`
+	TargetID   string     `json:"-"`
+	ReporterID string     `json:"-"`
+	Reason     Reason     `json:"reason"`
+	Detail     string     `json:"detail"`
+}
+
+func (p ReportPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.TargetType, validation.Required, validation.In(TargetTypes...)),
+		validation.Field(&p.TargetID, validation.Required, validation.When(p.TargetType == TargetComment, commentIDRule)),
+		validation.Field(&p.Reason, validation.Required, validation.In(Reasons...)),
+		validation.Field(&p.Detail, validation.When(p.Reason == ReasonOther, validation.Required), validation.Length(0, 500)),
+	)
+}
+
+type ListCasePayload struct {
+	Status     Status     `schema:"status" binding:"omitempty"`
+	TargetType TargetType `schema:"targetType" binding:"omitempty"`
+	Limit      int        `schema:"limit" binding:"omitempty"`
+	Offset     int        `schema:"offset" binding:"omitempty"`
+}
+
+func (p ListCasePayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Status, validation.In(Statuses...)),
+		validation.Field(&p.TargetType, validation.In(TargetTypes...)),
+		validation.Field(&p.Limit, validation.Min(0), validation.Max(100)),
+		validation.Field(&p.Offset, validation.Min(0)),
+	)
+}
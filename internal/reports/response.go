@@ -0,0 +1,30 @@
+package reports
+
+import "time"
+
+type CaseResponse struct {
+	ID           string         `json:"caseId"`
+	TargetType   TargetType     `json:"targetType"`
+	TargetID     string         `json:"targetId"`
+	TargetUserID string         `json:"targetUserId"`
+	Status       Status         `json:"status"`
+	ReportCount  int            `json:"reportCount"`
+	Reasons      map[Reason]int `json:"reasons"`
+	Hidden       bool           `json:"hidden"`
+	DecidedBy    *string        `json:"decidedBy"`
+	DecisionNote string         `json:"decisionNote"`
+	DecidedAt    *time.Time     `json:"decidedAt"`
+	CreatedAt    time.Time      `json:"createdAt"`
+}
+
+type CaseDetailResponse struct {
+	CaseResponse
+	Reports []ReportResponse `json:"reports"`
+}
+
+type ReportResponse struct {
+	ReporterID string    `json:"reporterId"`
+	Reason     Reason    `json:"reason"`
+	Detail     string    `json:"detail"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
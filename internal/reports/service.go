@@ -0,0 +1,44 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/id"
+)
+
+type Service interface {
+	Report(ctx context.Context, req ReportPayload) error
+}
+
+type reportService struct {
+	repository    Repository
+	hideThreshold int
+}
+
+// NewService creates a report service that hides posts and comments once hideThreshold users reported them,
+// a zero hideThreshold leaves them visible until an admin takes them down.
+func NewService(repository Repository, hideThreshold int) Service {
+	return &reportService{
+		repository:    repository,
+		hideThreshold: hideThreshold,
+	}
+}
+
+// Report implements Service.
+func (s *reportService) Report(ctx context.Context, req ReportPayload) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	return s.repository.Create(ctx, &Case{
+		ID:         id.GenerateStringID(16),
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+	}, &Report{
+		ID:         id.GenerateStringID(16),
+		ReporterID: req.ReporterID,
+		Reason:     req.Reason,
+		Detail:     req.Detail,
+	}, s.hideThreshold)
+}
@@ -0,0 +1,12 @@
+package session
+
+import "errors"
+
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionRevoked      = errors.New("session has been revoked")
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
+	ErrRefreshTokenExpired = errors.New("refresh token has expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+	ErrValidationFailed    = errors.New("validation failed")
+)
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/request"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+	tokenResp, err := h.service.Refresh(r.Context(), req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrRefreshTokenInvalid) || errors.Is(err, ErrRefreshTokenExpired) ||
+		errors.Is(err, ErrRefreshTokenReused) || errors.Is(err, ErrSessionRevoked) || errors.Is(err, ErrSessionNotFound) {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Token refreshed successfully",
+		Data:    tokenResp,
+	})
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := getSessionID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+	err = h.service.Revoke(r.Context(), sessionID)
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "User logged out successfully",
+	})
+}
+
+func getSessionID(r *http.Request) (string, error) {
+	if principal, ok := middleware.PrincipalFromContext(r.Context()); ok && principal.SessionID != "" {
+		return principal.SessionID, nil
+	}
+	slog.Error("cannot parse session value from context")
+	return "", errors.New("cannot parse session value from context")
+}
@@ -0,0 +1,152 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+)
+
+type Repository interface {
+	Create(ctx context.Context, session *Session, refreshToken *RefreshToken) error
+	GetByID(ctx context.Context, id string) (*Session, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	Rotate(ctx context.Context, usedTokenID string, refreshToken *RefreshToken) error
+	Revoke(ctx context.Context, id string) error
+	RevokeAllForUser(ctx context.Context, userID string) error
+	RevokeOthersForUser(ctx context.Context, userID string, keptID string) error
+}
+
+type dbRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+// Create implements Repository.
+func (d *dbRepository) Create(ctx context.Context, session *Session, refreshToken *RefreshToken) error {
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+				INSERT INTO sessions (
+					id, user_id
+				) VALUES (
+					$1, $2
+				)
+			`, session.ID, session.UserID)
+		if err != nil {
+			return err
+		}
+
+		return insertRefreshToken(ctx, tx, refreshToken)
+	})
+
+	return err
+}
+
+// GetByID implements Repository.
+func (d *dbRepository) GetByID(ctx context.Context, id string) (*Session, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT id, user_id, revoked_at, created_at
+		FROM sessions
+		WHERE id = $1;
+	`, id)
+
+	s := &Session{}
+	err := row.Scan(&s.ID, &s.UserID, &s.RevokedAt, &s.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetRefreshTokenByHash implements Repository.
+func (d *dbRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT id, session_id, token_hash, expires_at, used_at, created_at
+		FROM session_refresh_tokens
+		WHERE token_hash = $1;
+	`, tokenHash)
+
+	t := &RefreshToken{}
+	err := row.Scan(&t.ID, &t.SessionID, &t.TokenHash, &t.ExpiresAt, &t.UsedAt, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Rotate marks the used refresh token as consumed and stores its successor in the same session.
+// It returns ErrRefreshTokenReused when the used token had already been consumed.
+func (d *dbRepository) Rotate(ctx context.Context, usedTokenID string, refreshToken *RefreshToken) error {
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+				UPDATE session_refresh_tokens
+				SET used_at = current_timestamp
+				WHERE id = $1 AND used_at IS NULL
+			`, usedTokenID)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		return insertRefreshToken(ctx, tx, refreshToken)
+	})
+
+	return err
+}
+
+// Revoke implements Repository.
+func (d *dbRepository) Revoke(ctx context.Context, id string) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		UPDATE sessions
+		SET revoked_at = current_timestamp
+		WHERE id = $1 AND revoked_at IS NULL;
+	`, id)
+	return err
+}
+
+// RevokeAllForUser implements Repository.
+func (d *dbRepository) RevokeAllForUser(ctx context.Context, userID string) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		UPDATE sessions
+		SET revoked_at = current_timestamp
+		WHERE user_id = $1 AND revoked_at IS NULL;
+	`, userID)
+	return err
+}
+
+// RevokeOthersForUser revokes every session of the user but keptID.
+func (d *dbRepository) RevokeOthersForUser(ctx context.Context, userID string, keptID string) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		UPDATE sessions
+		SET revoked_at = current_timestamp
+		WHERE user_id = $1 AND id != $2 AND revoked_at IS NULL;
+	`, userID, keptID)
+	return err
+}
+
+func insertRefreshToken(ctx context.Context, tx *sql.Tx, refreshToken *RefreshToken) error {
+	_, err := tx.ExecContext(ctx, `
+			INSERT INTO session_refresh_tokens (
+				id, session_id, token_hash, expires_at
+			) VALUES (
+				$1, $2, $3, $4
+			)
+		`, refreshToken.ID, refreshToken.SessionID, refreshToken.TokenHash, refreshToken.ExpiresAt)
+	return err
+}
@@ -0,0 +1,13 @@
+package session
+
+import validation "github.com/go-ozzo/ozzo-validation/v4"
+
+type RefreshPayload struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (p RefreshPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.RefreshToken, validation.Required),
+	)
+}
@@ -0,0 +1,185 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/auth"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/id"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/jwt"
+)
+
+const (
+	accessTokenTTL  = time.Hour * 8
+	refreshTokenTTL = time.Hour * 24 * 30
+)
+
+type Service interface {
+	Issue(ctx context.Context, userID string) (*TokenResponse, error)
+	Refresh(ctx context.Context, req RefreshPayload) (*TokenResponse, error)
+	Revoke(ctx context.Context, sessionID string) error
+	RevokeAll(ctx context.Context, userID string) error
+	RevokeOthers(ctx context.Context, userID string, keptSessionID string) error
+	IsActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// RolesFunc returns the current roles of a user, which are granted to the access tokens of their sessions.
+type RolesFunc func(ctx context.Context, userID string) ([]string, error)
+
+type sessionService struct {
+	repository Repository
+	roles      RolesFunc
+}
+
+// NewService creates a session service, a nil roles grants every user auth.RoleUser.
+func NewService(repository Repository, roles RolesFunc) Service {
+	return &sessionService{
+		repository: repository,
+		roles:      roles,
+	}
+}
+
+// Issue starts a new session for the user and returns its first token pair.
+func (s *sessionService) Issue(ctx context.Context, userID string) (*TokenResponse, error) {
+	session := &Session{
+		ID:     id.GenerateStringID(16),
+		UserID: userID,
+	}
+	refreshToken, refreshTokenString, err := newRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repository.Create(ctx, session, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.signAccessToken(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshTokenString,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Presenting a refresh token
+// that was already exchanged revokes the whole session.
+func (s *sessionService) Refresh(ctx context.Context, req RefreshPayload) (*TokenResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	usedToken, err := s.repository.GetRefreshTokenByHash(ctx, hashRefreshToken(req.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.repository.GetByID(ctx, usedToken.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+	if usedToken.UsedAt != nil {
+		return nil, s.revokeOnReuse(ctx, session.ID)
+	}
+	if time.Now().After(usedToken.ExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	refreshToken, refreshTokenString, err := newRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repository.Rotate(ctx, usedToken.ID, refreshToken)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		return nil, s.revokeOnReuse(ctx, session.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.signAccessToken(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshTokenString,
+	}, nil
+}
+
+// Revoke implements Service.
+func (s *sessionService) Revoke(ctx context.Context, sessionID string) error {
+	return s.repository.Revoke(ctx, sessionID)
+}
+
+// RevokeAll ends every session of the user.
+func (s *sessionService) RevokeAll(ctx context.Context, userID string) error {
+	return s.repository.RevokeAllForUser(ctx, userID)
+}
+
+// RevokeOthers ends every session of the user but the one they are using.
+func (s *sessionService) RevokeOthers(ctx context.Context, userID string, keptSessionID string) error {
+	return s.repository.RevokeOthersForUser(ctx, userID, keptSessionID)
+}
+
+// IsActive implements Service.
+func (s *sessionService) IsActive(ctx context.Context, sessionID string) (bool, error) {
+	session, err := s.repository.GetByID(ctx, sessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return session.RevokedAt == nil, nil
+}
+
+// signAccessToken signs an access token for the session with the roles the user has now,
+// so that role changes apply from the next refresh.
+func (s *sessionService) signAccessToken(ctx context.Context, session *Session) (string, error) {
+	roles := []string{auth.RoleUser}
+	if s.roles != nil {
+		var err error
+		roles, err = s.roles(ctx, session.UserID)
+		if err != nil {
+			return "", err
+		}
+	}
+	return jwt.Sign(accessTokenTTL, session.UserID, session.ID, roles, auth.ScopesFor(roles))
+}
+
+func (s *sessionService) revokeOnReuse(ctx context.Context, sessionID string) error {
+	err := s.repository.Revoke(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func newRefreshToken(sessionID string) (*RefreshToken, string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, "", err
+	}
+	tokenString := base64.RawURLEncoding.EncodeToString(b)
+	return &RefreshToken{
+		ID:        id.GenerateStringID(16),
+		SessionID: sessionID,
+		TokenHash: hashRefreshToken(tokenString),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}, tokenString, nil
+}
+
+func hashRefreshToken(tokenString string) string {
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,19 @@
+package session
+
+import "time"
+
+type Session struct {
+	ID        string
+	UserID    string
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type RefreshToken struct {
+	ID        string
+	SessionID string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
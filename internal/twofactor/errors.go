@@ -0,0 +1,9 @@
+package twofactor
+
+import "errors"
+
+var (
+	ErrNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrCodeInvalid    = errors.New("two-factor code is invalid")
+)
@@ -0,0 +1,175 @@
+package twofactor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+)
+
+type Repository interface {
+	Save(ctx context.Context, enrollment *Enrollment) error
+	GetByUserID(ctx context.Context, userID string) (*Enrollment, error)
+	Confirm(ctx context.Context, userID string, step int64, recoveryCodes []RecoveryCode) error
+	UseStep(ctx context.Context, userID string, step int64) error
+	UseRecoveryCode(ctx context.Context, userID string, codeHash string) error
+	Delete(ctx context.Context, userID string) error
+}
+
+type dbRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+// Save stores a new unconfirmed enrollment, replacing the previous one unless it was confirmed.
+func (d *dbRepository) Save(ctx context.Context, enrollment *Enrollment) error {
+	res, err := d.db.DB().ExecContext(ctx, `
+		INSERT INTO user_totp (
+			user_id, secret
+		) VALUES (
+			$1, $2
+		)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = current_timestamp
+		WHERE user_totp.confirmed_at IS NULL;
+	`, enrollment.UserID, enrollment.Secret)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAlreadyEnabled
+	}
+	return nil
+}
+
+// GetByUserID implements Repository.
+func (d *dbRepository) GetByUserID(ctx context.Context, userID string) (*Enrollment, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT user_id, secret, confirmed_at, last_used_step, created_at
+		FROM user_totp
+		WHERE user_id = $1;
+	`, userID)
+
+	e := &Enrollment{}
+	err := row.Scan(&e.UserID, &e.Secret, &e.ConfirmedAt, &e.LastUsedStep, &e.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Confirm enables two-factor authentication for the user and replaces their recovery codes.
+func (d *dbRepository) Confirm(ctx context.Context, userID string, step int64, recoveryCodes []RecoveryCode) error {
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+				UPDATE user_totp
+				SET confirmed_at = current_timestamp, last_used_step = $2
+				WHERE user_id = $1 AND confirmed_at IS NULL
+			`, userID, step)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrAlreadyEnabled
+		}
+
+		_, err = tx.ExecContext(ctx, `
+				DELETE FROM user_recovery_codes
+				WHERE user_id = $1
+			`, userID)
+		if err != nil {
+			return err
+		}
+		for _, code := range recoveryCodes {
+			_, err = tx.ExecContext(ctx, `
+					INSERT INTO user_recovery_codes (
+						id, user_id, code_hash
+					) VALUES (
+						$1, $2, $3
+					)
+				`, code.ID, code.UserID, code.CodeHash)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return err
+}
+
+// UseStep records the time step of an accepted code, it fails with ErrCodeInvalid when a code of
+// that step or a later one was already used.
+func (d *dbRepository) UseStep(ctx context.Context, userID string, step int64) error {
+	res, err := d.db.DB().ExecContext(ctx, `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2;
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCodeInvalid
+	}
+	return nil
+}
+
+// UseRecoveryCode consumes an unused recovery code of the user, it fails with ErrCodeInvalid when there is none.
+func (d *dbRepository) UseRecoveryCode(ctx context.Context, userID string, codeHash string) error {
+	res, err := d.db.DB().ExecContext(ctx, `
+		UPDATE user_recovery_codes
+		SET used_at = current_timestamp
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+	`, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCodeInvalid
+	}
+	return nil
+}
+
+// Delete removes the enrollment and the recovery codes of the user.
+func (d *dbRepository) Delete(ctx context.Context, userID string) error {
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+				DELETE FROM user_recovery_codes
+				WHERE user_id = $1
+			`, userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+				DELETE FROM user_totp
+				WHERE user_id = $1
+			`, userID)
+		return err
+	})
+
+	return err
+}
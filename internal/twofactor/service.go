@@ -0,0 +1,161 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/id"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/totp"
+)
+
+const (
+	recoveryCodeCount = 10
+	// 6 random bytes encode to 10 base32 characters, formatted as two groups of recoveryCodeGroupSize
+	recoveryCodeBytes     = 6
+	recoveryCodeGroupSize = 5
+)
+
+var (
+	issuer = os.Getenv("TOTP_ISSUER")
+
+	recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+)
+
+type Service interface {
+	Enroll(ctx context.Context, userID string, accountName string) (*EnrollResponse, error)
+	Confirm(ctx context.Context, userID string, code string) (*RecoveryCodesResponse, error)
+	IsEnabled(ctx context.Context, userID string) (bool, error)
+	Verify(ctx context.Context, userID string, code string) (bool, error)
+	Disable(ctx context.Context, userID string) error
+}
+
+type twoFactorService struct {
+	repository Repository
+}
+
+func NewService(repository Repository) Service {
+	return &twoFactorService{repository: repository}
+}
+
+// Enroll generates a new TOTP secret for the user, which has to be confirmed before it is used at login.
+func (s *twoFactorService) Enroll(ctx context.Context, userID string, accountName string) (*EnrollResponse, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	err = s.repository.Save(ctx, &Enrollment{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	name := issuer
+	if name == "" {
+		name = "Segokuning"
+	}
+	return &EnrollResponse{
+		Secret:     secret,
+		OtpauthURI: totp.URI(name, accountName, secret),
+	}, nil
+}
+
+// Confirm enables two-factor authentication with a first code from the authenticator,
+// and returns the recovery codes of the user. Only their hashes are stored.
+func (s *twoFactorService) Confirm(ctx context.Context, userID string, code string) (*RecoveryCodesResponse, error) {
+	enrollment, err := s.repository.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.ConfirmedAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+	step, ok := totp.Validate(code, enrollment.Secret, time.Now())
+	if !ok {
+		return nil, ErrCodeInvalid
+	}
+
+	plainCodes := make([]string, recoveryCodeCount)
+	recoveryCodes := make([]RecoveryCode, recoveryCodeCount)
+	for i := range plainCodes {
+		plainCodes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		recoveryCodes[i] = RecoveryCode{
+			ID:       id.GenerateStringID(16),
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(userID, plainCodes[i]),
+		}
+	}
+	err = s.repository.Confirm(ctx, userID, step, recoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: plainCodes}, nil
+}
+
+// IsEnabled implements Service.
+func (s *twoFactorService) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	enrollment, err := s.repository.GetByUserID(ctx, userID)
+	if errors.Is(err, ErrNotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return enrollment.ConfirmedAt != nil, nil
+}
+
+// Verify checks a TOTP code, or consumes a recovery code, of a user with two-factor authentication enabled.
+// It reports whether a recovery code was used.
+func (s *twoFactorService) Verify(ctx context.Context, userID string, code string) (bool, error) {
+	enrollment, err := s.repository.GetByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if enrollment.ConfirmedAt == nil {
+		return false, ErrNotEnrolled
+	}
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(code, enrollment.Secret, time.Now())
+		if !ok {
+			return false, ErrCodeInvalid
+		}
+		return false, s.repository.UseStep(ctx, userID, step)
+	}
+	err = s.repository.UseRecoveryCode(ctx, userID, hashRecoveryCode(userID, code))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Disable turns two-factor authentication off, the user has to enroll again to turn it back on.
+func (s *twoFactorService) Disable(ctx context.Context, userID string) error {
+	return s.repository.Delete(ctx, userID)
+}
+
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeBytes)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	code := recoveryCodeEncoding.EncodeToString(b)
+	return code[:recoveryCodeGroupSize] + "-" + code[recoveryCodeGroupSize:], nil
+}
+
+// hashRecoveryCode salts the normalized code with the user ID, so that codes can be typed without the dash or in upper case.
+func hashRecoveryCode(userID string, code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(userID + ":" + normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,20 @@
+package twofactor
+
+import "time"
+
+// Enrollment holds the TOTP secret of a user. Two-factor authentication is enabled once it is confirmed.
+type Enrollment struct {
+	UserID       string
+	Secret       string
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// RecoveryCode can be used once instead of a TOTP code, when the user lost their authenticator.
+type RecoveryCode struct {
+	ID       string
+	UserID   string
+	CodeHash string
+	UsedAt   *time.Time
+}
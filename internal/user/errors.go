@@ -5,10 +5,18 @@ import "errors"
 var (
 	ErrUserNotFound                 = errors.New("user not found")
 	ErrWrongPassword                = errors.New("wrong password")
+	ErrInvalidCredentials           = errors.New("invalid credentials")
+	ErrUserSuspended                = errors.New("user is suspended")
+	ErrUserBanned                   = errors.New("user is banned")
+	ErrPasswordResetRequired        = errors.New("password has to be reset")
+	ErrChallengeInvalid             = errors.New("challenge token is invalid or expired")
 	ErrUserPhoneNumberAlreadyExists = errors.New("user phone number already exists")
 	ErrUserEmailAlreadyExists       = errors.New("user email already exists")
 	ErrUserHasEmail                 = errors.New("user already has email")
 	ErrUserHasPhoneNumber           = errors.New("user already has phone number")
+	ErrUserHasNoEmail               = errors.New("user has no email")
+	ErrUserHasNoPhoneNumber         = errors.New("user has no phone number")
+	ErrLastCredential               = errors.New("user must keep at least one of phone/email")
 	ErrValidationFailed             = errors.New("validation failed")
 	ErrCredentialMustExists         = errors.New("credential must be one of phone/email")
 )
@@ -1,13 +1,20 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/citadel-corp/segokuning-social-app/internal/common/cursor"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/request"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/citadel-corp/segokuning-social-app/internal/loginattempt"
+	"github.com/citadel-corp/segokuning-social-app/internal/twofactor"
+	"github.com/citadel-corp/segokuning-social-app/internal/verification"
 	"github.com/gorilla/schema"
 )
 
@@ -70,14 +77,23 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	userResp, err := h.service.Login(r.Context(), req)
-	if errors.Is(err, ErrUserNotFound) {
-		response.JSON(w, http.StatusNotFound, response.ResponseBody{
-			Message: "Not found",
+	var lockedErr *loginattempt.LockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: "Too many requests",
 			Error:   err.Error(),
 		})
 		return
 	}
-	if errors.Is(err, ErrWrongPassword) {
+	if errors.Is(err, ErrUserSuspended) || errors.Is(err, ErrUserBanned) || errors.Is(err, ErrPasswordResetRequired) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrInvalidCredentials) {
 		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
 			Message: "Bad request",
 			Error:   err.Error(),
@@ -98,12 +114,400 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	message := "User logged successfully"
+	if userResp.TwoFactorRequired {
+		message = "Two-factor authentication required"
+	}
+	if userResp.VerificationRequired {
+		message = "Credential verification required, a code was sent to it"
+	}
 	response.JSON(w, http.StatusOK, response.ResponseBody{
-		Message: "User logged successfully",
+		Message: message,
 		Data:    userResp,
 	})
 }
 
+func (h *Handler) LoginVerify(w http.ResponseWriter, r *http.Request) {
+	var req LoginVerifyPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+	userResp, err := h.service.LoginVerify(r.Context(), req)
+	if errors.Is(err, ErrChallengeInvalid) {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrValidationFailed) || errors.Is(err, verification.ErrCodeInvalid) || errors.Is(err, verification.ErrCodeExpired) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrUserEmailAlreadyExists) || errors.Is(err, ErrUserPhoneNumberAlreadyExists) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrUserSuspended) || errors.Is(err, ErrUserBanned) || errors.Is(err, ErrPasswordResetRequired) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	message := "User logged successfully"
+	if userResp.TwoFactorRequired {
+		message = "Two-factor authentication required"
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: message,
+		Data:    userResp,
+	})
+}
+
+func (h *Handler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req LoginTwoFactorPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+	tokenResp, err := h.service.LoginTwoFactor(r.Context(), req)
+	var lockedErr *loginattempt.LockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: "Too many requests",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrChallengeInvalid) || errors.Is(err, twofactor.ErrNotEnrolled) {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrValidationFailed) || errors.Is(err, twofactor.ErrCodeInvalid) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrUserSuspended) || errors.Is(err, ErrUserBanned) || errors.Is(err, ErrPasswordResetRequired) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "User logged successfully",
+		Data:    tokenResp,
+	})
+}
+
+func (h *Handler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+	var req EnrollTwoFactorPayload
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+	enrollResp, err := h.service.EnrollTwoFactor(r.Context(), req, userID)
+	var lockedErr *loginattempt.LockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: "Too many requests",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrValidationFailed) || errors.Is(err, ErrWrongPassword) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, twofactor.ErrAlreadyEnabled) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Two-factor authentication enrolled, confirm it with a code",
+		Data:    enrollResp,
+	})
+}
+
+func (h *Handler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+	var req TwoFactorCodePayload
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+	recoveryCodesResp, err := h.service.ConfirmTwoFactor(r.Context(), req, userID)
+	if errors.Is(err, ErrValidationFailed) || errors.Is(err, twofactor.ErrCodeInvalid) || errors.Is(err, twofactor.ErrNotEnrolled) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, twofactor.ErrAlreadyEnabled) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Two-factor authentication enabled",
+		Data:    recoveryCodesResp,
+	})
+}
+
+func (h *Handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+	var req DisableTwoFactorPayload
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+	err = h.service.DisableTwoFactor(r.Context(), req, userID)
+	var lockedErr *loginattempt.LockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: "Too many requests",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrValidationFailed) || errors.Is(err, ErrWrongPassword) || errors.Is(err, twofactor.ErrCodeInvalid) ||
+		errors.Is(err, twofactor.ErrNotEnrolled) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+	err = h.service.ForgotPassword(r.Context(), req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, verification.ErrTooManyRequests) {
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: "Too many requests",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Password reset code sent if the credential is registered",
+	})
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+	err = h.service.ResetPassword(r.Context(), req)
+	if errors.Is(err, ErrValidationFailed) || errors.Is(err, verification.ErrCodeInvalid) || errors.Is(err, verification.ErrCodeExpired) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Password reset successfully",
+	})
+}
+
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+	var req ChangePasswordPayload
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+	err = h.service.ChangePassword(r.Context(), req, userID)
+	var lockedErr *loginattempt.LockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: "Too many requests",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrValidationFailed) || errors.Is(err, ErrWrongPassword) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Password changed successfully",
+	})
+}
+
 func (h *Handler) LinkEmail(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserID(r)
 	if err != nil {
@@ -145,6 +549,13 @@ func (h *Handler) LinkEmail(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if errors.Is(err, verification.ErrTooManyRequests) {
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: "Too many requests",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
 			Message: "Internal server error",
@@ -153,7 +564,7 @@ func (h *Handler) LinkEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	response.JSON(w, http.StatusOK, response.ResponseBody{
-		Message: "User email linked successfully",
+		Message: "Verification code sent to email",
 	})
 }
 
@@ -198,6 +609,13 @@ func (h *Handler) LinkPhoneNumber(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if errors.Is(err, verification.ErrTooManyRequests) {
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: "Too many requests",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
 			Message: "Internal server error",
@@ -206,7 +624,100 @@ func (h *Handler) LinkPhoneNumber(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	response.JSON(w, http.StatusOK, response.ResponseBody{
-		Message: "Successfully linked phone number",
+		Message: "Verification code sent to phone number",
+	})
+}
+
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	h.verifyCredential(w, r, h.service.VerifyEmail, "User email linked successfully")
+}
+
+func (h *Handler) VerifyPhoneNumber(w http.ResponseWriter, r *http.Request) {
+	h.verifyCredential(w, r, h.service.VerifyPhoneNumber, "Successfully linked phone number")
+}
+
+func (h *Handler) verifyCredential(w http.ResponseWriter, r *http.Request,
+	verify func(ctx context.Context, req VerifyCredentialPayload, userID string) error, successMessage string) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+	var req VerifyCredentialPayload
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+	err = verify(r.Context(), req, userID)
+	if errors.Is(err, ErrValidationFailed) || errors.Is(err, verification.ErrCodeInvalid) || errors.Is(err, verification.ErrCodeExpired) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrUserEmailAlreadyExists) || errors.Is(err, ErrUserPhoneNumberAlreadyExists) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: successMessage,
+	})
+}
+
+func (h *Handler) UnlinkEmail(w http.ResponseWriter, r *http.Request) {
+	h.unlinkCredential(w, r, h.service.UnlinkEmail, "User email unlinked successfully")
+}
+
+func (h *Handler) UnlinkPhoneNumber(w http.ResponseWriter, r *http.Request) {
+	h.unlinkCredential(w, r, h.service.UnlinkPhoneNumber, "User phone number unlinked successfully")
+}
+
+func (h *Handler) unlinkCredential(w http.ResponseWriter, r *http.Request,
+	unlink func(ctx context.Context, userID string) error, successMessage string) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   err.Error(),
+		})
+		return
+	}
+	err = unlink(r.Context(), userID)
+	if errors.Is(err, ErrUserHasNoEmail) || errors.Is(err, ErrUserHasNoPhoneNumber) || errors.Is(err, ErrLastCredential) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: successMessage,
 	})
 }
 
@@ -323,6 +834,13 @@ func (h *Handler) ListUser(w http.ResponseWriter, r *http.Request) {
 	req.UserID = userID
 
 	usersResp, pagination, err := h.service.List(r.Context(), req)
+	if errors.Is(err, cursor.ErrInvalidCursor) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
 			Message: "Internal server error",
@@ -338,8 +856,8 @@ func (h *Handler) ListUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func getUserID(r *http.Request) (string, error) {
-	if authValue, ok := r.Context().Value(middleware.ContextAuthKey{}).(string); ok {
-		return authValue, nil
+	if principal, ok := middleware.PrincipalFromContext(r.Context()); ok {
+		return principal.UserID, nil
 	}
 	slog.Error("cannot parse auth value from context")
 	return "", errors.New("cannot parse auth value from context")
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/citadel-corp/segokuning-social-app/internal/common/cursor"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -19,7 +21,21 @@ type Repository interface {
 	GetByPhoneNumber(ctx context.Context, phoneNumber string) (*User, error)
 	GetByID(ctx context.Context, id string) (*User, error)
 	Update(ctx context.Context, user *User) error
+	UpdatePassword(ctx context.Context, id string, hashedPassword string) error
+	RehashPassword(ctx context.Context, id string, currentHash string, hashedPassword string) error
+	UpdateStatus(ctx context.Context, id string, status Status) error
+	RequirePasswordReset(ctx context.Context, id string) error
 	List(ctx context.Context, filter ListUserPayload) ([]UserListResponse, *response.Pagination, error)
+	Search(ctx context.Context, filter SearchUserPayload) ([]User, *response.Pagination, error)
+}
+
+// userCursor is the keyset position of the last user in a page, along with the ordering it was taken from.
+type userCursor struct {
+	SortBy      string    `json:"sortBy"`
+	OrderBy     string    `json:"orderBy"`
+	FriendCount int       `json:"friendCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ID          string    `json:"id"`
 }
 
 type dbRepository struct {
@@ -83,7 +99,8 @@ func (d *dbRepository) CreateWithPhoneNumber(ctx context.Context, user *User) er
 // GetByEmail implements Repository.
 func (d *dbRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
 	getUserQuery := `
-		SELECT id, name, email, phone_number, friend_count, image_url, hashed_password, created_at FROM users
+		SELECT id, name, email, phone_number, friend_count, image_url, hashed_password, email_verified_at,
+			phone_number_verified_at, role, status, password_reset_required, created_at FROM users
 		WHERE email = $1;
 	`
 	row := d.db.DB().QueryRowContext(ctx, getUserQuery, email)
@@ -93,7 +110,8 @@ func (d *dbRepository) GetByEmail(ctx context.Context, email string) (*User, err
 // GetByPhoneNumber implements Repository.
 func (d *dbRepository) GetByPhoneNumber(ctx context.Context, phoneNumber string) (*User, error) {
 	getUserQuery := `
-		SELECT id, name, email, phone_number, friend_count, image_url, hashed_password, created_at FROM users
+		SELECT id, name, email, phone_number, friend_count, image_url, hashed_password, email_verified_at,
+			phone_number_verified_at, role, status, password_reset_required, created_at FROM users
 		WHERE phone_number = $1;
 	`
 	row := d.db.DB().QueryRowContext(ctx, getUserQuery, phoneNumber)
@@ -102,7 +120,8 @@ func (d *dbRepository) GetByPhoneNumber(ctx context.Context, phoneNumber string)
 
 func (d *dbRepository) GetByID(ctx context.Context, id string) (*User, error) {
 	getUserQuery := `
-		SELECT id, name, email, phone_number, friend_count, image_url, hashed_password, created_at FROM users
+		SELECT id, name, email, phone_number, friend_count, image_url, hashed_password, email_verified_at,
+			phone_number_verified_at, role, status, password_reset_required, created_at FROM users
 		WHERE id = $1;
 	`
 	row := d.db.DB().QueryRowContext(ctx, getUserQuery, id)
@@ -117,10 +136,30 @@ func (d *dbRepository) Update(ctx context.Context, user *User) error {
 		email = $2,
 		phone_number = $3,
 		friend_count = $4,
-		image_url = $5
-		WHERE id = $6;
+		image_url = $5,
+		email_verified_at = $6,
+		phone_number_verified_at = $7
+		WHERE id = $8;
 	`
-	_, err := d.db.DB().ExecContext(ctx, updateQuery, user.Name, user.Email, user.PhoneNumber, user.FriendCount, user.ImageURL, user.ID)
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		// a verified credential is taken over from the user still holding it unverified, unless it is the only
+		// credential they can log in with, in which case the update conflicts
+		if user.Email != nil && user.EmailVerifiedAt != nil {
+			err := releaseUnverifiedEmail(ctx, tx, *user.Email, user.ID)
+			if err != nil {
+				return err
+			}
+		}
+		if user.PhoneNumber != nil && user.PhoneNumberVerifiedAt != nil {
+			err := releaseUnverifiedPhoneNumber(ctx, tx, *user.PhoneNumber, user.ID)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, updateQuery, user.Name, user.Email, user.PhoneNumber, user.FriendCount, user.ImageURL,
+			user.EmailVerifiedAt, user.PhoneNumberVerifiedAt, user.ID)
+		return err
+	})
 	var pgErr *pgconn.PgError
 	if err != nil {
 		if errors.As(err, &pgErr) {
@@ -141,6 +180,131 @@ func (d *dbRepository) Update(ctx context.Context, user *User) error {
 	return nil
 }
 
+// UpdatePassword implements Repository.
+func (d *dbRepository) UpdatePassword(ctx context.Context, id string, hashedPassword string) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		UPDATE users
+		SET hashed_password = $1, password_reset_required = false
+		WHERE id = $2;
+	`, hashedPassword, id)
+	return err
+}
+
+// RehashPassword replaces the hash of an unchanged password with an upgraded one. Unlike UpdatePassword
+// it leaves password_reset_required as is, and does nothing when the password was changed meanwhile.
+func (d *dbRepository) RehashPassword(ctx context.Context, id string, currentHash string, hashedPassword string) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+		UPDATE users
+		SET hashed_password = $1
+		WHERE id = $2 AND hashed_password = $3;
+	`, hashedPassword, id, currentHash)
+	return err
+}
+
+// UpdateStatus implements Repository.
+func (d *dbRepository) UpdateStatus(ctx context.Context, id string, status Status) error {
+	res, err := d.db.DB().ExecContext(ctx, `
+		UPDATE users
+		SET status = $1
+		WHERE id = $2;
+	`, status, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// RequirePasswordReset blocks the logins of the user until their password is changed by UpdatePassword.
+func (d *dbRepository) RequirePasswordReset(ctx context.Context, id string) error {
+	res, err := d.db.DB().ExecContext(ctx, `
+		UPDATE users
+		SET password_reset_required = true
+		WHERE id = $1;
+	`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Search finds users whose email or phone number contains the given values.
+func (d *dbRepository) Search(ctx context.Context, filter SearchUserPayload) ([]User, *response.Pagination, error) {
+	var (
+		whereStatement string
+		args           []interface{}
+		columnCtr      int = 1
+	)
+
+	if filter.Limit == 0 {
+		filter.Limit = 5
+	}
+
+	pagination := &response.Pagination{
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+
+	if filter.Email != "" {
+		whereStatement = insertWhereStatement(len(args) > 0, whereStatement)
+		whereStatement = fmt.Sprintf("%s lower(users.email) LIKE CONCAT('%%',$%d::text,'%%')", whereStatement, columnCtr)
+		args = append(args, strings.ToLower(filter.Email))
+		columnCtr++
+	}
+
+	if filter.Phone != "" {
+		whereStatement = insertWhereStatement(len(args) > 0, whereStatement)
+		whereStatement = fmt.Sprintf("%s users.phone_number LIKE CONCAT('%%',$%d::text,'%%')", whereStatement, columnCtr)
+		args = append(args, filter.Phone)
+		columnCtr++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER() AS total_count, id, name, email, phone_number, friend_count, image_url, email_verified_at,
+			phone_number_verified_at, role, status, password_reset_required, created_at
+		FROM users
+		%s
+		ORDER BY users.created_at desc, users.id desc
+		LIMIT $%d OFFSET $%d;
+	`, whereStatement, columnCtr, columnCtr+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := d.db.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&pagination.Total, &u.ID, &u.Name, &u.Email, &u.PhoneNumber, &u.FriendCount, &u.ImageURL, &u.EmailVerifiedAt,
+			&u.PhoneNumberVerifiedAt, &u.Role, &u.Status, &u.PasswordResetRequired, &u.CreatedAt); err != nil {
+			return users, nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return users, nil, err
+	}
+
+	return users, pagination, nil
+}
+
 func (d *dbRepository) List(ctx context.Context, filter ListUserPayload) ([]UserListResponse, *response.Pagination, error) {
 	var users []UserListResponse
 	var pagination *response.Pagination
@@ -187,14 +351,13 @@ func (d *dbRepository) List(ctx context.Context, filter ListUserPayload) ([]User
 		orderBy = "desc"
 	}
 
-	switch filter.SortBy {
-	case SortByFriendCount:
-		orderStatement = fmt.Sprintf("%s ORDER BY users.friend_count %s", orderStatement, orderBy)
-	case SortByCreatedAt:
-		orderStatement = fmt.Sprintf("%s ORDER BY users.created_at %s", orderStatement, orderBy)
-	default:
-		orderStatement = fmt.Sprintf("%s ORDER BY users.created_at %s", orderStatement, orderBy)
+	sortColumn := "users.created_at"
+	if filter.SortBy == SortByFriendCount {
+		sortColumn = "users.friend_count"
+	} else {
+		filter.SortBy = SortByCreatedAt
 	}
+	orderStatement = fmt.Sprintf("%s ORDER BY %s %s, users.id %s", orderStatement, sortColumn, orderBy, orderBy)
 
 	if filter.Limit == 0 {
 		filter.Limit = 5
@@ -207,14 +370,46 @@ func (d *dbRepository) List(ctx context.Context, filter ListUserPayload) ([]User
 		Offset: filter.Offset,
 	}
 
+	// counting every matching user is what makes deep pages slow, cursor mode skips it
+	countStatement := "COUNT(*) OVER()"
+	if filter.Cursor != "" {
+		var position userCursor
+		if err := cursor.Decode(filter.Cursor, &position); err != nil {
+			return nil, nil, err
+		}
+		if position.SortBy != filter.SortBy || position.OrderBy != orderBy {
+			return nil, nil, cursor.ErrInvalidCursor
+		}
+
+		comparator := "<"
+		if orderBy == "asc" {
+			comparator = ">"
+		}
+		whereStatement = insertWhereStatement(len(args) > 0, whereStatement)
+		whereStatement = fmt.Sprintf("%s (%s, users.id) %s ($%d, $%d)", whereStatement, sortColumn, comparator, columnCtr, columnCtr+1)
+		if filter.SortBy == SortByFriendCount {
+			args = append(args, position.FriendCount)
+		} else {
+			args = append(args, position.CreatedAt)
+		}
+		args = append(args, position.ID)
+		columnCtr += 2
+
+		countStatement = "0"
+		filter.Offset = 0
+		pagination.Offset = 0
+		pagination.Cursor = filter.Cursor
+	}
+
 	selectStatement = fmt.Sprintf(`
-		SELECT COUNT(*) OVER() AS total_count, users.id as userId, users.name as name, users.image_url as imageUrl,
+		SELECT %s AS total_count, users.id as userId, users.name as name, users.image_url as imageUrl,
 			users.friend_count as friendCount, users.created_at as createdAt
 		FROM users
-	%s`, selectStatement)
+	%s`, countStatement, selectStatement)
 
+	// fetch one more user than requested to know whether there is a next page
 	paginationStatement = fmt.Sprintf("%s LIMIT $%d", paginationStatement, columnCtr)
-	args = append(args, filter.Limit)
+	args = append(args, filter.Limit+1)
 	columnCtr++
 
 	paginationStatement = fmt.Sprintf("%s OFFSET $%d", paginationStatement, columnCtr)
@@ -241,6 +436,21 @@ func (d *dbRepository) List(ctx context.Context, filter ListUserPayload) ([]User
 		return users, nil, err
 	}
 
+	if len(users) > filter.Limit {
+		users = users[:filter.Limit]
+		last := users[len(users)-1]
+		pagination.NextCursor, err = cursor.Encode(userCursor{
+			SortBy:      filter.SortBy,
+			OrderBy:     orderBy,
+			FriendCount: last.FriendCount,
+			CreatedAt:   last.CreatedAt,
+			ID:          last.ID,
+		})
+		if err != nil {
+			return users, nil, err
+		}
+	}
+
 	return users, pagination, nil
 }
 
@@ -253,7 +463,8 @@ func insertWhereStatement(condition bool, statement string) string {
 
 func (d *dbRepository) scanUser(row *sql.Row) (*User, error) {
 	u := &User{}
-	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.PhoneNumber, &u.FriendCount, &u.ImageURL, &u.HashedPassword, &u.CreatedAt)
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.PhoneNumber, &u.FriendCount, &u.ImageURL, &u.HashedPassword, &u.EmailVerifiedAt,
+		&u.PhoneNumberVerifiedAt, &u.Role, &u.Status, &u.PasswordResetRequired, &u.CreatedAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrUserNotFound
 	}
@@ -262,3 +473,25 @@ func (d *dbRepository) scanUser(row *sql.Row) (*User, error) {
 	}
 	return u, nil
 }
+
+// releaseUnverifiedEmail removes the email from another user who never verified it and can still log in with
+// their phone number, so that it can be verified by its owner.
+func releaseUnverifiedEmail(ctx context.Context, tx *sql.Tx, email string, userID string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET email = NULL
+		WHERE email = $1 AND id != $2 AND email_verified_at IS NULL AND phone_number IS NOT NULL;
+	`, email, userID)
+	return err
+}
+
+// releaseUnverifiedPhoneNumber removes the phone number from another user who never verified it and can still log in
+// with their email, so that it can be verified by its owner.
+func releaseUnverifiedPhoneNumber(ctx context.Context, tx *sql.Tx, phoneNumber string, userID string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET phone_number = NULL
+		WHERE phone_number = $1 AND id != $2 AND phone_number_verified_at IS NULL AND email IS NOT NULL;
+	`, phoneNumber, userID)
+	return err
+}
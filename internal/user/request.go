@@ -51,6 +51,50 @@ func (p LoginPayload) Validate() error {
 	)
 }
 
+type ForgotPasswordPayload struct {
+	CredentialType  string `json:"credentialType"`
+	CredentialValue string `json:"credentialValue"`
+}
+
+func (p ForgotPasswordPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CredentialType, validation.Required, validation.In("phone", "email")),
+		validation.Field(&p.CredentialValue, validation.Required, validation.
+			When(p.CredentialType == "email", is.EmailFormat).
+			Else(phoneNumberValidationRule, validation.Length(7, 13))),
+	)
+}
+
+type ResetPasswordPayload struct {
+	CredentialType  string `json:"credentialType"`
+	CredentialValue string `json:"credentialValue"`
+	Code            string `json:"code"`
+	Password        string `json:"password"`
+}
+
+func (p ResetPasswordPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CredentialType, validation.Required, validation.In("phone", "email")),
+		validation.Field(&p.CredentialValue, validation.Required, validation.
+			When(p.CredentialType == "email", is.EmailFormat).
+			Else(phoneNumberValidationRule, validation.Length(7, 13))),
+		validation.Field(&p.Code, validation.Required, validation.Length(6, 6), is.Digit),
+		validation.Field(&p.Password, validation.Required, validation.Length(5, 15)),
+	)
+}
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+func (p ChangePasswordPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CurrentPassword, validation.Required, validation.Length(5, 15)),
+		validation.Field(&p.NewPassword, validation.Required, validation.Length(5, 15)),
+	)
+}
+
 type LinkEmailPayload struct {
 	Email string `json:"email"`
 }
@@ -71,6 +115,77 @@ func (p LinkPhoneNumberPayload) Validate() error {
 	)
 }
 
+type VerifyCredentialPayload struct {
+	Code string `json:"code"`
+}
+
+func (p VerifyCredentialPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Code, validation.Required, validation.Length(6, 6), is.Digit),
+	)
+}
+
+// TwoFactorCodePayload.Code is a TOTP code, or a recovery code where recovery codes are accepted.
+type TwoFactorCodePayload struct {
+	Code string `json:"code"`
+}
+
+func (p TwoFactorCodePayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Code, validation.Required, validation.Length(6, 11)),
+	)
+}
+
+type EnrollTwoFactorPayload struct {
+	Password string `json:"password"`
+}
+
+func (p EnrollTwoFactorPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Password, validation.Required, validation.Length(5, 15)),
+	)
+}
+
+// DisableTwoFactorPayload.Code is a TOTP or recovery code.
+type DisableTwoFactorPayload struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func (p DisableTwoFactorPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Password, validation.Required, validation.Length(5, 15)),
+		validation.Field(&p.Code, validation.Required, validation.Length(6, 11)),
+	)
+}
+
+type LoginTwoFactorPayload struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+}
+
+func (p LoginTwoFactorPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ChallengeToken, validation.Required),
+		validation.Field(&p.Code, validation.Required, validation.Length(6, 11)),
+	)
+}
+
+type LoginVerifyPayload struct {
+	ChallengeToken string `json:"challengeToken"`
+	CredentialType string `json:"credentialType"`
+	Code           string `json:"code"`
+}
+
+func (p LoginVerifyPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ChallengeToken, validation.Required),
+		validation.Field(&p.CredentialType, validation.Required, validation.In("phone", "email")),
+		validation.Field(&p.Code, validation.Required, validation.Length(6, 6), is.Digit),
+	)
+}
+
+// UpdateUserPayload.ImageURL is either an image URL or the ID of an image uploaded through the image endpoint.
 type UpdateUserPayload struct {
 	ImageURL string `json:"imageUrl"`
 	Name     string `json:"name"`
@@ -78,11 +193,32 @@ type UpdateUserPayload struct {
 
 func (p UpdateUserPayload) Validate() error {
 	return validation.ValidateStruct(&p,
-		validation.Field(&p.ImageURL, validation.Required, urlValidationRule),
+		validation.Field(&p.ImageURL, validation.Required, validation.When(!p.IsImageID(), urlValidationRule)),
 		validation.Field(&p.Name, validation.Required, validation.Length(5, 50)),
 	)
 }
 
+// IsImageID reports whether ImageURL holds an image ID instead of a URL.
+func (p UpdateUserPayload) IsImageID() bool {
+	return is.UUID.Validate(p.ImageURL) == nil
+}
+
+// SearchUserPayload is used by admins to find users by a part of their email or phone number.
+type SearchUserPayload struct {
+	Email  string `schema:"email" binding:"omitempty"`
+	Phone  string `schema:"phone" binding:"omitempty"`
+	Limit  int    `schema:"limit" binding:"omitempty"`
+	Offset int    `schema:"offset" binding:"omitempty"`
+}
+
+func (p SearchUserPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Email, validation.Required.When(p.Phone == "").Error("email or phone is required")),
+		validation.Field(&p.Limit, validation.Min(0), validation.Max(100)),
+		validation.Field(&p.Offset, validation.Min(0)),
+	)
+}
+
 var (
 	SortByFriendCount string = "friendCount"
 	SortByCreatedAt   string = "createdAt"
@@ -94,6 +230,7 @@ type ListUserPayload struct {
 	OnlyFriend  bool
 	UserID      string
 	Search      string `schema:"search" binding:"omitempty"`
+	Cursor      string `schema:"cursor" binding:"omitempty"`
 	Limit       int
 	Offset      int
 	SortBy      string
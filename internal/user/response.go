@@ -3,17 +3,27 @@ package user
 import "time"
 
 type UserRegisterResponse struct {
-	Email       *string `json:"email,omitempty"`
-	Phone       *string `json:"phone,omitempty"`
-	Name        string  `json:"name"`
-	AccessToken string  `json:"accessToken"`
+	Email        *string `json:"email,omitempty"`
+	Phone        *string `json:"phone,omitempty"`
+	Name         string  `json:"name"`
+	AccessToken  string  `json:"accessToken"`
+	RefreshToken string  `json:"refreshToken"`
 }
 
 type UserLoginResponse struct {
-	Email       string `json:"email"`
-	Phone       string `json:"phone"`
-	Name        string `json:"name"`
-	AccessToken string `json:"accessToken"`
+	Email              string `json:"email"`
+	Phone              string `json:"phone"`
+	Name               string `json:"name"`
+	CredentialVerified bool   `json:"credentialVerified"`
+	AccessToken        string `json:"accessToken"`
+	RefreshToken       string `json:"refreshToken"`
+	// with two-factor authentication enabled, the tokens are left empty and ChallengeToken has to be
+	// exchanged for them with a code
+	TwoFactorRequired bool `json:"twoFactorRequired"`
+	// with UNVERIFIED_LOGIN=block, a login through an unverified credential leaves the tokens empty, and
+	// ChallengeToken has to be given along with the code sent to the credential to verify it
+	VerificationRequired bool   `json:"verificationRequired"`
+	ChallengeToken       string `json:"challengeToken,omitempty"`
 }
 
 type UserListResponse struct {
@@ -2,30 +2,86 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
 	"time"
 
+	"github.com/citadel-corp/segokuning-social-app/internal/audit"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/id"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/jwt"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/notifier"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/password"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/citadel-corp/segokuning-social-app/internal/image"
+	"github.com/citadel-corp/segokuning-social-app/internal/loginattempt"
+	"github.com/citadel-corp/segokuning-social-app/internal/session"
+	"github.com/citadel-corp/segokuning-social-app/internal/twofactor"
+	"github.com/citadel-corp/segokuning-social-app/internal/verification"
+)
+
+const (
+	// UnverifiedLoginBlock rejects logins through a credential that was never verified
+	UnverifiedLoginBlock = "block"
+	// UnverifiedLoginFlag allows them, and reports the credential as unverified in the login response
+	UnverifiedLoginFlag = "flag"
+
+	// challengeTokenTTL is how long a user has to give their second factor after the password
+	challengeTokenTTL = 5 * time.Minute
+	// verificationTokenTTL is how long a user blocked by an unverified credential has to verify it,
+	// as long as the code sent to it is valid
+	verificationTokenTTL = 15 * time.Minute
+)
+
+var (
+	unverifiedLogin = os.Getenv("UNVERIFIED_LOGIN")
 )
 
 type Service interface {
 	Create(ctx context.Context, req CreateUserPayload) (*UserRegisterResponse, error)
 	Login(ctx context.Context, req LoginPayload) (*UserLoginResponse, error)
+	LoginTwoFactor(ctx context.Context, req LoginTwoFactorPayload) (*session.TokenResponse, error)
+	LoginVerify(ctx context.Context, req LoginVerifyPayload) (*UserLoginResponse, error)
+	EnrollTwoFactor(ctx context.Context, req EnrollTwoFactorPayload, userID string) (*twofactor.EnrollResponse, error)
+	ConfirmTwoFactor(ctx context.Context, req TwoFactorCodePayload, userID string) (*twofactor.RecoveryCodesResponse, error)
+	DisableTwoFactor(ctx context.Context, req DisableTwoFactorPayload, userID string) error
 	LinkEmail(ctx context.Context, req LinkEmailPayload, userID string) error
 	LinkPhoneNumber(ctx context.Context, req LinkPhoneNumberPayload, userID string) error
+	VerifyEmail(ctx context.Context, req VerifyCredentialPayload, userID string) error
+	VerifyPhoneNumber(ctx context.Context, req VerifyCredentialPayload, userID string) error
 	Update(ctx context.Context, req UpdateUserPayload, userID string) error
+	ForgotPassword(ctx context.Context, req ForgotPasswordPayload) error
+	ResetPassword(ctx context.Context, req ResetPasswordPayload) error
+	ChangePassword(ctx context.Context, req ChangePasswordPayload, userID string) error
+	UnlinkEmail(ctx context.Context, userID string) error
+	UnlinkPhoneNumber(ctx context.Context, userID string) error
 	List(ctx context.Context, req ListUserPayload) ([]UserListResponse, *response.Pagination, error)
 }
 
 type userService struct {
-	repository Repository
+	repository          Repository
+	sessionService      session.Service
+	imageService        image.Service
+	verificationService verification.Service
+	auditService        audit.Service
+	loginAttemptService loginattempt.Service
+	twoFactorService    twofactor.Service
 }
 
-func NewService(repository Repository) Service {
-	return &userService{repository: repository}
+func NewService(repository Repository, sessionService session.Service, imageService image.Service,
+	verificationService verification.Service, auditService audit.Service, loginAttemptService loginattempt.Service,
+	twoFactorService twofactor.Service) Service {
+	return &userService{
+		repository:          repository,
+		sessionService:      sessionService,
+		imageService:        imageService,
+		verificationService: verificationService,
+		auditService:        auditService,
+		loginAttemptService: loginAttemptService,
+		twoFactorService:    twoFactorService,
+	}
 }
 
 func (s *userService) Create(ctx context.Context, req CreateUserPayload) (*UserRegisterResponse, error) {
@@ -58,45 +114,163 @@ func (s *userService) Create(ctx context.Context, req CreateUserPayload) (*UserR
 	if err != nil {
 		return nil, err
 	}
-	// create access token with signed jwt
-	accessToken, err := jwt.Sign(time.Hour*8, fmt.Sprint(user.ID))
+	// start a session with signed access token and refresh token
+	tokens, err := s.sessionService.Issue(ctx, user.ID)
 	if err != nil {
 		return nil, err
 	}
 	return &UserRegisterResponse{
-		Phone:       user.PhoneNumber,
-		Email:       user.Email,
-		Name:        req.Name,
-		AccessToken: accessToken,
+		Phone:        user.PhoneNumber,
+		Email:        user.Email,
+		Name:         req.Name,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
+// Login starts a session for the credential. Failed attempts are throttled per credential and per client IP,
+// and an unknown credential fails the same way as a wrong password.
 func (s *userService) Login(ctx context.Context, req LoginPayload) (*UserLoginResponse, error) {
 	err := req.Validate()
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
 	}
-	var user *User
-	if req.CredentialType == "email" {
-		user, err = s.repository.GetByEmail(ctx, req.CredentialValue)
-	} else {
-		user, err = s.repository.GetByPhoneNumber(ctx, req.CredentialValue)
+	credentialKey := loginattempt.CredentialKey(req.CredentialType, req.CredentialValue)
+	var ipKeys []loginattempt.Key
+	if ip := middleware.ClientFromContext(ctx).IP; ip != "" {
+		ipKeys = append(ipKeys, loginattempt.IPKey(ip))
 	}
+	// the attempt is counted as a failure before the password is compared, and taken back once it matches.
+	// The IP goes first, so that a throttled IP cannot lock out the credentials it tries
+	err = s.loginAttemptService.Attempt(ctx, append(ipKeys, credentialKey)...)
 	if err != nil {
 		return nil, err
 	}
-	match, err := password.Matches(req.Password, user.HashedPassword)
+	user, err := s.getByCredential(ctx, req.CredentialType, req.CredentialValue)
+	if errors.Is(err, ErrUserNotFound) {
+		password.CompareDummy(req.Password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	match, needsRehash, err := password.Matches(req.Password, user.HashedPassword)
 	if err != nil {
 		return nil, err
 	}
 	if !match {
-		return nil, ErrWrongPassword
+		return nil, ErrInvalidCredentials
+	}
+	err = s.loginAttemptService.Reset(ctx, credentialKey)
+	if err != nil {
+		return nil, err
+	}
+	err = s.loginAttemptService.Refund(ctx, ipKeys...)
+	if err != nil {
+		return nil, err
+	}
+	err = checkCanLogin(user)
+	if err != nil {
+		return nil, err
+	}
+	if needsRehash {
+		s.rehashPassword(ctx, user, req.Password)
+	}
+	credentialVerified := user.EmailVerifiedAt != nil
+	if req.CredentialType == "phone" {
+		credentialVerified = user.PhoneNumberVerifiedAt != nil
+	}
+	if !credentialVerified && unverifiedLogin == UnverifiedLoginBlock {
+		return s.requireVerification(ctx, user, req.CredentialType, req.CredentialValue)
+	}
+	return s.startLogin(ctx, user, credentialVerified)
+}
+
+// LoginVerify verifies the credential of a login blocked by UnverifiedLoginBlock with the challenge token
+// returned by Login and the code sent to the credential, then carries on with the login.
+func (s *userService) LoginVerify(ctx context.Context, req LoginVerifyPayload) (*UserLoginResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	claims, err := jwt.VerifyChallenge(req.ChallengeToken, jwt.PurposeVerifyCredential)
+	if err != nil {
+		return nil, ErrChallengeInvalid
+	}
+	user, err := s.repository.GetByID(ctx, claims.Subject)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, ErrChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = checkCanLogin(user)
+	if err != nil {
+		return nil, err
+	}
+	verify := s.VerifyEmail
+	if req.CredentialType == "phone" {
+		verify = s.VerifyPhoneNumber
+	}
+	err = verify(ctx, VerifyCredentialPayload{Code: req.Code}, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	user, err = s.repository.GetByID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.startLogin(ctx, user, true)
+}
+
+// requireVerification sends a code to the credential of a login blocked by UnverifiedLoginBlock, and returns
+// the challenge token that LoginVerify takes along with the code instead of tokens.
+func (s *userService) requireVerification(ctx context.Context, user *User, credentialType string, credentialValue string) (*UserLoginResponse, error) {
+	purpose := verification.PurposeVerifyEmail
+	if credentialType == "phone" {
+		purpose = verification.PurposeVerifyPhone
+	}
+	err := s.verificationService.Issue(ctx, user.ID, purpose, notifier.Channel(credentialType), credentialValue)
+	// a code sent by an earlier login is still valid
+	if err != nil && !errors.Is(err, verification.ErrTooManyRequests) {
+		return nil, err
+	}
+	challengeToken, err := jwt.SignChallenge(verificationTokenTTL, user.ID, jwt.PurposeVerifyCredential)
+	if err != nil {
+		return nil, err
+	}
+	resp := newLoginResponse(user, false)
+	resp.VerificationRequired = true
+	resp.ChallengeToken = challengeToken
+	return resp, nil
+}
+
+// startLogin starts a session for a user who passed the login checks, or returns a two-factor challenge.
+func (s *userService) startLogin(ctx context.Context, user *User, credentialVerified bool) (*UserLoginResponse, error) {
+	resp := newLoginResponse(user, credentialVerified)
+	twoFactorEnabled, err := s.twoFactorService.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactorEnabled {
+		resp.TwoFactorRequired = true
+		resp.ChallengeToken, err = jwt.SignChallenge(challengeTokenTTL, user.ID, jwt.PurposeTwoFactor)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
 	}
-	// create access token with signed jwt
-	accessToken, err := jwt.Sign(time.Hour*8, fmt.Sprint(user.ID))
+	// start a session with signed access token and refresh token
+	tokens, err := s.sessionService.Issue(ctx, user.ID)
 	if err != nil {
 		return nil, err
 	}
+	resp.AccessToken = tokens.AccessToken
+	resp.RefreshToken = tokens.RefreshToken
+	return resp, nil
+}
+
+func newLoginResponse(user *User, credentialVerified bool) *UserLoginResponse {
 	email := ""
 	if user.Email != nil {
 		email = *user.Email
@@ -106,14 +280,138 @@ func (s *userService) Login(ctx context.Context, req LoginPayload) (*UserLoginRe
 		phone = *user.PhoneNumber
 	}
 	return &UserLoginResponse{
-		Email:       email,
-		Phone:       phone,
-		Name:        user.Name,
-		AccessToken: accessToken,
-	}, nil
+		Email:              email,
+		Phone:              phone,
+		Name:               user.Name,
+		CredentialVerified: credentialVerified,
+	}
 }
 
-// LinkEmail implements Service.
+// LoginTwoFactor exchanges the challenge token returned by Login for a session, with a TOTP or recovery code.
+func (s *userService) LoginTwoFactor(ctx context.Context, req LoginTwoFactorPayload) (*session.TokenResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	claims, err := jwt.VerifyChallenge(req.ChallengeToken, jwt.PurposeTwoFactor)
+	if err != nil {
+		return nil, ErrChallengeInvalid
+	}
+	userID := claims.Subject
+	// the user may have been suspended, banned or required to reset their password since the challenge was issued
+	user, err := s.repository.GetByID(ctx, userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, ErrChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = checkCanLogin(user)
+	if err != nil {
+		return nil, err
+	}
+	// the attempt is counted before the code is checked, so that parallel guesses are throttled too
+	attemptKey := loginattempt.TwoFactorKey(userID)
+	err = s.loginAttemptService.Attempt(ctx, attemptKey)
+	if err != nil {
+		return nil, err
+	}
+	usedRecoveryCode, err := s.twoFactorService.Verify(ctx, userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	err = s.loginAttemptService.Reset(ctx, attemptKey)
+	if err != nil {
+		return nil, err
+	}
+	if usedRecoveryCode {
+		err = s.auditService.Record(ctx, userID, audit.ActionRecoveryCodeUsed, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.sessionService.Issue(ctx, userID)
+}
+
+// EnrollTwoFactor starts enabling two-factor authentication after checking the password of the user,
+// the returned URI is added to an authenticator app.
+func (s *userService) EnrollTwoFactor(ctx context.Context, req EnrollTwoFactorPayload, userID string) (*twofactor.EnrollResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	user, err := s.repository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkPassword(ctx, user, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	accountName := user.ID
+	if user.Email != nil {
+		accountName = *user.Email
+	} else if user.PhoneNumber != nil {
+		accountName = *user.PhoneNumber
+	}
+	return s.twoFactorService.Enroll(ctx, user.ID, accountName)
+}
+
+// ConfirmTwoFactor enables two-factor authentication with a first code from the authenticator app.
+func (s *userService) ConfirmTwoFactor(ctx context.Context, req TwoFactorCodePayload, userID string) (*twofactor.RecoveryCodesResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	recoveryCodes, err := s.twoFactorService.Confirm(ctx, userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	err = s.auditService.Record(ctx, userID, audit.ActionTwoFactorEnabled, "")
+	if err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off with the password of the user and a TOTP or recovery code.
+func (s *userService) DisableTwoFactor(ctx context.Context, req DisableTwoFactorPayload, userID string) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	user, err := s.repository.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = s.checkPassword(ctx, user, req.Password)
+	if err != nil {
+		return err
+	}
+	attemptKey := loginattempt.TwoFactorKey(userID)
+	err = s.loginAttemptService.Attempt(ctx, attemptKey)
+	if err != nil {
+		return err
+	}
+	_, err = s.twoFactorService.Verify(ctx, userID, req.Code)
+	if err != nil {
+		return err
+	}
+	err = s.loginAttemptService.Reset(ctx, attemptKey)
+	if err != nil {
+		return err
+	}
+	err = s.twoFactorService.Disable(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.auditService.Record(ctx, userID, audit.ActionTwoFactorDisabled, "")
+}
+
+// LinkEmail sends a code to the email, the email is linked once the code is given to VerifyEmail,
+// replacing the current email of the user if any. An email the user registered with but never verified
+// can be linked again to verify it. An email another user holds without having verified it can be taken over,
+// as long as that user keeps their phone number to log in with.
 func (s *userService) LinkEmail(ctx context.Context, req LinkEmailPayload, userID string) error {
 	err := req.Validate()
 	if err != nil {
@@ -123,14 +421,23 @@ func (s *userService) LinkEmail(ctx context.Context, req LinkEmailPayload, userI
 	if err != nil {
 		return err
 	}
-	if user.Email != nil {
+	if user.Email != nil && *user.Email == req.Email && user.EmailVerifiedAt != nil {
 		return ErrUserHasEmail
 	}
-	user.Email = &req.Email
-	return s.repository.Update(ctx, user)
+	owner, err := s.repository.GetByEmail(ctx, req.Email)
+	if err == nil && owner.ID != user.ID && (owner.EmailVerifiedAt != nil || owner.PhoneNumber == nil) {
+		return ErrUserEmailAlreadyExists
+	}
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return err
+	}
+	return s.verificationService.Issue(ctx, user.ID, verification.PurposeVerifyEmail, notifier.ChannelEmail, req.Email)
 }
 
-// LinkPhoneNumber implements Service.
+// LinkPhoneNumber sends a code to the phone number, the phone number is linked once the code is given to VerifyPhoneNumber,
+// replacing the current phone number of the user if any. A phone number the user registered with but never verified
+// can be linked again to verify it. A phone number another user holds without having verified it can be taken over,
+// as long as that user keeps their email to log in with.
 func (s *userService) LinkPhoneNumber(ctx context.Context, req LinkPhoneNumberPayload, userID string) error {
 	err := req.Validate()
 	if err != nil {
@@ -140,11 +447,117 @@ func (s *userService) LinkPhoneNumber(ctx context.Context, req LinkPhoneNumberPa
 	if err != nil {
 		return err
 	}
-	if user.PhoneNumber != nil {
+	if user.PhoneNumber != nil && *user.PhoneNumber == req.Phone && user.PhoneNumberVerifiedAt != nil {
 		return ErrUserHasPhoneNumber
 	}
-	user.PhoneNumber = &req.Phone
-	return s.repository.Update(ctx, user)
+	owner, err := s.repository.GetByPhoneNumber(ctx, req.Phone)
+	if err == nil && owner.ID != user.ID && (owner.PhoneNumberVerifiedAt != nil || owner.Email == nil) {
+		return ErrUserPhoneNumberAlreadyExists
+	}
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return err
+	}
+	return s.verificationService.Issue(ctx, user.ID, verification.PurposeVerifyPhone, notifier.ChannelPhone, req.Phone)
+}
+
+// VerifyEmail links and verifies the email the code was sent to by LinkEmail.
+func (s *userService) VerifyEmail(ctx context.Context, req VerifyCredentialPayload, userID string) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	email, err := s.verificationService.Verify(ctx, userID, verification.PurposeVerifyEmail, req.Code)
+	if err != nil {
+		return err
+	}
+	user, err := s.repository.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	action, detail := audit.ActionEmailLinked, email
+	if user.Email != nil && *user.Email != email {
+		action, detail = audit.ActionEmailReplaced, fmt.Sprintf("%s -> %s", *user.Email, email)
+	}
+	verifiedAt := time.Now()
+	user.Email = &email
+	user.EmailVerifiedAt = &verifiedAt
+	err = s.repository.Update(ctx, user)
+	if err != nil {
+		return err
+	}
+	return s.auditService.Record(ctx, user.ID, action, detail)
+}
+
+// VerifyPhoneNumber links and verifies the phone number the code was sent to by LinkPhoneNumber.
+func (s *userService) VerifyPhoneNumber(ctx context.Context, req VerifyCredentialPayload, userID string) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	phone, err := s.verificationService.Verify(ctx, userID, verification.PurposeVerifyPhone, req.Code)
+	if err != nil {
+		return err
+	}
+	user, err := s.repository.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	action, detail := audit.ActionPhoneNumberLinked, phone
+	if user.PhoneNumber != nil && *user.PhoneNumber != phone {
+		action, detail = audit.ActionPhoneNumberReplaced, fmt.Sprintf("%s -> %s", *user.PhoneNumber, phone)
+	}
+	verifiedAt := time.Now()
+	user.PhoneNumber = &phone
+	user.PhoneNumberVerifiedAt = &verifiedAt
+	err = s.repository.Update(ctx, user)
+	if err != nil {
+		return err
+	}
+	return s.auditService.Record(ctx, user.ID, action, detail)
+}
+
+// UnlinkEmail removes the email of the user, as long as the user still has a phone number to log in with.
+func (s *userService) UnlinkEmail(ctx context.Context, userID string) error {
+	user, err := s.repository.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Email == nil {
+		return ErrUserHasNoEmail
+	}
+	if user.PhoneNumber == nil {
+		return ErrLastCredential
+	}
+	email := *user.Email
+	user.Email = nil
+	user.EmailVerifiedAt = nil
+	err = s.repository.Update(ctx, user)
+	if err != nil {
+		return err
+	}
+	return s.auditService.Record(ctx, user.ID, audit.ActionEmailUnlinked, email)
+}
+
+// UnlinkPhoneNumber removes the phone number of the user, as long as the user still has an email to log in with.
+func (s *userService) UnlinkPhoneNumber(ctx context.Context, userID string) error {
+	user, err := s.repository.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.PhoneNumber == nil {
+		return ErrUserHasNoPhoneNumber
+	}
+	if user.Email == nil {
+		return ErrLastCredential
+	}
+	phone := *user.PhoneNumber
+	user.PhoneNumber = nil
+	user.PhoneNumberVerifiedAt = nil
+	err = s.repository.Update(ctx, user)
+	if err != nil {
+		return err
+	}
+	return s.auditService.Record(ctx, user.ID, audit.ActionPhoneNumberUnlinked, phone)
 }
 
 // Update implements Service.
@@ -157,12 +570,176 @@ func (s *userService) Update(ctx context.Context, req UpdateUserPayload, userID
 	if err != nil {
 		return err
 	}
-	user.ImageURL = &req.ImageURL
+	imageURL := req.ImageURL
+	if req.IsImageID() {
+		imageURL, err = s.imageService.Reference(ctx, req.ImageURL, userID, image.UserReference(userID))
+		if errors.Is(err, image.ErrImageNotFound) || errors.Is(err, image.ErrImageInUse) {
+			return fmt.Errorf("%w: imageUrl: %w", ErrValidationFailed, err)
+		}
+	} else {
+		// an external URL replaces the uploaded image that may have been used before
+		err = s.imageService.ReleaseReference(ctx, image.UserReference(userID))
+	}
+	if err != nil {
+		return err
+	}
+	user.ImageURL = &imageURL
 	user.Name = req.Name
 	return s.repository.Update(ctx, user)
 }
 
+// ForgotPassword sends a password reset code to the credential. Unknown credentials, and credentials
+// that were sent too many codes, are ignored alike so that the endpoint does not tell which credentials are registered.
+func (s *userService) ForgotPassword(ctx context.Context, req ForgotPasswordPayload) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	user, err := s.getByCredential(ctx, req.CredentialType, req.CredentialValue)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = s.verificationService.Issue(ctx, user.ID, verification.PurposePasswordReset,
+		notifier.Channel(req.CredentialType), req.CredentialValue)
+	if errors.Is(err, verification.ErrTooManyRequests) {
+		return nil
+	}
+	return err
+}
+
+// ResetPassword sets a new password with a code sent by ForgotPassword, and ends every session of the user.
+func (s *userService) ResetPassword(ctx context.Context, req ResetPasswordPayload) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	user, err := s.getByCredential(ctx, req.CredentialType, req.CredentialValue)
+	if errors.Is(err, ErrUserNotFound) {
+		return verification.ErrCodeInvalid
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.verificationService.Verify(ctx, user.ID, verification.PurposePasswordReset, req.Code)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := password.Hash(req.Password)
+	if err != nil {
+		return err
+	}
+	err = s.repository.UpdatePassword(ctx, user.ID, hashedPassword)
+	if err != nil {
+		return err
+	}
+	err = s.auditService.Record(ctx, user.ID, audit.ActionPasswordReset, "")
+	if err != nil {
+		return err
+	}
+	// a new password lifts the lockout caused by attempts with the old one
+	err = s.loginAttemptService.Reset(ctx, loginattempt.CredentialKey(req.CredentialType, req.CredentialValue))
+	if err != nil {
+		return err
+	}
+	return s.sessionService.RevokeAll(ctx, user.ID)
+}
+
+// ChangePassword replaces the password of the user after checking the current one, and ends every other
+// session of the user.
+func (s *userService) ChangePassword(ctx context.Context, req ChangePasswordPayload, userID string) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	user, err := s.repository.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = s.checkPassword(ctx, user, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := password.Hash(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	err = s.repository.UpdatePassword(ctx, user.ID, hashedPassword)
+	if err != nil {
+		return err
+	}
+	err = s.auditService.Record(ctx, user.ID, audit.ActionPasswordChanged, "")
+	if err != nil {
+		return err
+	}
+	principal, _ := middleware.PrincipalFromContext(ctx)
+	return s.sessionService.RevokeOthers(ctx, user.ID, principal.SessionID)
+}
+
+// RolesFunc returns the roles of users from the repository, for the access tokens of their sessions.
+func RolesFunc(repository Repository) session.RolesFunc {
+	return func(ctx context.Context, userID string) ([]string, error) {
+		user, err := repository.GetByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return []string{user.Role}, nil
+	}
+}
+
 func (s *userService) List(ctx context.Context, req ListUserPayload) ([]UserListResponse, *response.Pagination, error) {
 	req.WithoutUser = true
 	return s.repository.List(ctx, req)
 }
+
+// rehashPassword upgrades the stored hash of a password that was just verified. Failing to do so does not
+// fail the login, the hash is upgraded on a later one.
+func (s *userService) rehashPassword(ctx context.Context, user *User, plaintextPassword string) {
+	hashedPassword, err := password.Hash(plaintextPassword)
+	if err == nil {
+		err = s.repository.RehashPassword(ctx, user.ID, user.HashedPassword, hashedPassword)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Cannot rehash password", slog.String("userID", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// checkPassword compares the current password given by a signed in user. Attempts are throttled like logins,
+// so that a stolen session cannot be used to guess the password.
+func (s *userService) checkPassword(ctx context.Context, user *User, plaintextPassword string) error {
+	attemptKey := loginattempt.PasswordKey(user.ID)
+	err := s.loginAttemptService.Attempt(ctx, attemptKey)
+	if err != nil {
+		return err
+	}
+	match, _, err := password.Matches(plaintextPassword, user.HashedPassword)
+	if err != nil {
+		return err
+	}
+	if !match {
+		return ErrWrongPassword
+	}
+	return s.loginAttemptService.Reset(ctx, attemptKey)
+}
+
+// checkCanLogin refuses users who were suspended, banned or required to reset their password by an admin.
+func checkCanLogin(user *User) error {
+	switch {
+	case user.Status == StatusSuspended:
+		return ErrUserSuspended
+	case user.Status == StatusBanned:
+		return ErrUserBanned
+	case user.PasswordResetRequired:
+		return ErrPasswordResetRequired
+	}
+	return nil
+}
+
+func (s *userService) getByCredential(ctx context.Context, credentialType string, credentialValue string) (*User, error) {
+	if credentialType == "email" {
+		return s.repository.GetByEmail(ctx, credentialValue)
+	}
+	return s.repository.GetByPhoneNumber(ctx, credentialValue)
+}
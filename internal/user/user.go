@@ -2,13 +2,29 @@ package user
 
 import "time"
 
+type Status string
+
+var (
+	StatusActive    Status = "active"
+	StatusSuspended Status = "suspended"
+	StatusBanned    Status = "banned"
+)
+
 type User struct {
-	ID             string
-	Name           string
-	Email          *string
-	PhoneNumber    *string
-	FriendCount    int
-	ImageURL       *string
-	HashedPassword string
-	CreatedAt      time.Time
+	ID          string
+	Name        string
+	Email       *string
+	PhoneNumber *string
+	FriendCount int
+	ImageURL    *string
+	// EmailVerifiedAt and PhoneNumberVerifiedAt are set once the user proved they own the credential
+	EmailVerifiedAt       *time.Time
+	PhoneNumberVerifiedAt *time.Time
+	HashedPassword        string
+	Role                  string
+	// Status is set by admins, only active users can log in and have their posts shown
+	Status Status
+	// PasswordResetRequired blocks logins until the password is reset through ForgotPassword
+	PasswordResetRequired bool
+	CreatedAt             time.Time
 }
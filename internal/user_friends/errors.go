@@ -18,4 +18,8 @@ var (
 	ErrCannotAddSelf       = Response{Code: http.StatusBadRequest, Message: "Cannot add self as friend"}
 	ErrCannotDeleteSelf    = Response{Code: http.StatusBadRequest, Message: "Cannot delete self as friend"}
 	ErrNotFriend           = Response{Code: http.StatusBadRequest, Message: "Cannot delete non friend"}
+
+	ErrFriendRequestAlreadyExists = Response{Code: http.StatusConflict, Message: "Friend request is already pending"}
+	ErrFriendRequestNotFound      = Response{Code: http.StatusNotFound, Message: "Friend request is not found"}
+	ErrFriendRequestNotPending    = Response{Code: http.StatusBadRequest, Message: "Friend request is no longer pending"}
 )
@@ -1,12 +1,15 @@
 package userfriends
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/citadel-corp/segokuning-social-app/internal/common/middleware"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/request"
 	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+	"github.com/gorilla/mux"
 )
 
 type Handler struct {
@@ -93,9 +96,104 @@ func (h *Handler) DeleteUserFriends(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+func (h *Handler) AcceptFriendRequest(w http.ResponseWriter, r *http.Request) {
+	h.respondFriendRequest(w, r, h.service.AcceptRequest)
+}
+
+func (h *Handler) RejectFriendRequest(w http.ResponseWriter, r *http.Request) {
+	h.respondFriendRequest(w, r, h.service.RejectRequest)
+}
+
+func (h *Handler) CancelFriendRequest(w http.ResponseWriter, r *http.Request) {
+	h.respondFriendRequest(w, r, h.service.CancelRequest)
+}
+
+func (h *Handler) ListIncomingFriendRequests(w http.ResponseWriter, r *http.Request) {
+	h.listFriendRequests(w, r, FriendRequestIncoming)
+}
+
+func (h *Handler) ListOutgoingFriendRequests(w http.ResponseWriter, r *http.Request) {
+	h.listFriendRequests(w, r, FriendRequestOutgoing)
+}
+
+func (h *Handler) respondFriendRequest(w http.ResponseWriter, r *http.Request, respond func(ctx context.Context, req RespondFriendRequestPayload) Response) {
+	var req RespondFriendRequestPayload
+	var err error
+
+	userID, err := getUserID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	req.LoggedUserID = userID
+
+	req.RequestID, err = strconv.ParseUint(mux.Vars(r)["requestId"], 10, 64)
+	if err != nil {
+		response.JSON(w, ErrFriendRequestNotFound.Code, response.ResponseBody{
+			Message: ErrFriendRequestNotFound.Message,
+		})
+		return
+	}
+
+	err = req.Validate()
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	resp := respond(r.Context(), req)
+	response.JSON(w, resp.Code, response.ResponseBody{
+		Message: resp.Message,
+		Error:   resp.Error,
+	})
+}
+
+func (h *Handler) listFriendRequests(w http.ResponseWriter, r *http.Request, direction string) {
+	userID, err := getUserID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	req := ListFriendRequestPayload{
+		LoggedUserID: userID,
+		Direction:    direction,
+	}
+
+	var params = r.URL.Query()
+	if v, ok := request.CheckPositiveInt(params, "limit"); ok {
+		req.Limit = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if v, ok := request.CheckPositiveInt(params, "offset"); ok {
+		req.Offset = v
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp := h.service.ListRequests(r.Context(), req)
+	response.JSON(w, resp.Code, response.ResponseBody{
+		Message: resp.Message,
+		Data:    resp.Data,
+		Meta:    resp.Meta,
+		Error:   resp.Error,
+	})
+}
+
 func getUserID(r *http.Request) (string, error) {
-	if authValue, ok := r.Context().Value(middleware.ContextAuthKey{}).(string); ok {
-		return authValue, nil
+	if principal, ok := middleware.PrincipalFromContext(r.Context()); ok {
+		return principal.UserID, nil
 	}
 
 	return "", errors.New("unauthorized")
@@ -3,15 +3,21 @@ package userfriends
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
 )
 
 type Repository interface {
-	AddFriend(ctx context.Context, userFriend *UserFriends) error
 	RemoveFriend(ctx context.Context, userID string, friendID string) error
 	GetByFriendID(ctx context.Context, userID string, friendID string) (*UserFriends, error)
 	ListByUserID(ctx context.Context, userID string) ([]*UserFriends, error)
+	CreateRequest(ctx context.Context, friendRequest *FriendRequest) error
+	GetRequestByID(ctx context.Context, id uint64) (*FriendRequest, error)
+	AcceptRequest(ctx context.Context, friendRequest *FriendRequest) error
+	CloseRequest(ctx context.Context, id uint64, status FriendRequestStatus) error
+	ListRequests(ctx context.Context, filter ListFriendRequestPayload) ([]FriendRequestResponse, *response.Pagination, error)
 }
 
 type dbRepository struct {
@@ -22,49 +28,6 @@ func NewRepository(db *db.DB) Repository {
 	return &dbRepository{db: db}
 }
 
-func (d *dbRepository) AddFriend(ctx context.Context, userFriend *UserFriends) error {
-	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
-		// add user's friend
-		_, err := tx.ExecContext(ctx, `
-				INSERT INTO user_friends (
-					user_id, friend_id
-				) VALUES (
-					$1, $2
-				)
-			`, userFriend.UserID, userFriend.FriendID)
-		if err != nil {
-			return err
-		}
-
-		// add user as friend's friend
-		_, err = tx.ExecContext(ctx, `
-				INSERT INTO user_friends (
-					user_id, friend_id
-				) VALUES (
-					$1, $2
-				)
-			`, userFriend.FriendID, userFriend.UserID)
-		if err != nil {
-			return err
-		}
-
-		// add friendCount to user and friend
-		_, err = tx.ExecContext(ctx, `
-				UPDATE users
-				SET friend_count = friend_count + 1
-				WHERE id = $1
-				OR id = $2
-			`, userFriend.UserID, userFriend.FriendID)
-		if err != nil {
-			return err
-		}
-
-		return nil
-	})
-
-	return err
-}
-
 func (d *dbRepository) RemoveFriend(ctx context.Context, userID string, friendID string) error {
 	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
 		_, err := tx.ExecContext(ctx, `
@@ -138,3 +101,163 @@ func (d *dbRepository) ListByUserID(ctx context.Context, userID string) ([]*User
 
 	return res, nil
 }
+
+// CreateRequest implements Repository.
+func (d *dbRepository) CreateRequest(ctx context.Context, friendRequest *FriendRequest) error {
+	row := d.db.DB().QueryRowContext(ctx, `
+			INSERT INTO friend_requests (
+				sender_id, receiver_id
+			) VALUES (
+				$1, $2
+			)
+			RETURNING id, status, created_at
+		`, friendRequest.SenderID, friendRequest.ReceiverID)
+	return row.Scan(&friendRequest.ID, &friendRequest.Status, &friendRequest.CreatedAt)
+}
+
+// GetRequestByID implements Repository.
+func (d *dbRepository) GetRequestByID(ctx context.Context, id uint64) (*FriendRequest, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT id, sender_id, receiver_id, status, responded_at, created_at FROM friend_requests
+		WHERE id = $1;
+	`, id)
+
+	var fr FriendRequest
+	err := row.Scan(&fr.ID, &fr.SenderID, &fr.ReceiverID, &fr.Status, &fr.RespondedAt, &fr.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fr, nil
+}
+
+// AcceptRequest closes a pending friend request and adds the friendship in both directions.
+// It returns sql.ErrNoRows when the request is no longer pending.
+func (d *dbRepository) AcceptRequest(ctx context.Context, friendRequest *FriendRequest) error {
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		err := closeRequest(ctx, tx, friendRequest.ID, FriendRequestAccepted)
+		if err != nil {
+			return err
+		}
+
+		// add user's friend
+		_, err = tx.ExecContext(ctx, `
+				INSERT INTO user_friends (
+					user_id, friend_id
+				) VALUES (
+					$1, $2
+				)
+			`, friendRequest.SenderID, friendRequest.ReceiverID)
+		if err != nil {
+			return err
+		}
+
+		// add user as friend's friend
+		_, err = tx.ExecContext(ctx, `
+				INSERT INTO user_friends (
+					user_id, friend_id
+				) VALUES (
+					$1, $2
+				)
+			`, friendRequest.ReceiverID, friendRequest.SenderID)
+		if err != nil {
+			return err
+		}
+
+		// add friendCount to user and friend
+		_, err = tx.ExecContext(ctx, `
+				UPDATE users
+				SET friend_count = friend_count + 1
+				WHERE id = $1
+				OR id = $2
+			`, friendRequest.SenderID, friendRequest.ReceiverID)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// CloseRequest marks a pending friend request as rejected or cancelled.
+// It returns sql.ErrNoRows when the request is no longer pending.
+func (d *dbRepository) CloseRequest(ctx context.Context, id uint64, status FriendRequestStatus) error {
+	err := d.db.StartTx(ctx, func(tx *sql.Tx) error {
+		return closeRequest(ctx, tx, id, status)
+	})
+
+	return err
+}
+
+// ListRequests implements Repository.
+func (d *dbRepository) ListRequests(ctx context.Context, filter ListFriendRequestPayload) ([]FriendRequestResponse, *response.Pagination, error) {
+	var requests []FriendRequestResponse
+	var pagination *response.Pagination
+
+	// incoming requests show the sender, outgoing requests show the receiver
+	ownColumn, otherColumn := "receiver_id", "sender_id"
+	if filter.Direction == FriendRequestOutgoing {
+		ownColumn, otherColumn = "sender_id", "receiver_id"
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = 5
+	}
+
+	pagination = &response.Pagination{
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER() AS total_count, fr.id, fr.created_at,
+			u.id as userId, u.name as name, u.image_url as imageUrl, u.friend_count as friendCount
+		FROM friend_requests fr
+		JOIN users u ON u.id = fr.%s
+		WHERE fr.%s = $1 AND fr.status = $2
+		ORDER BY fr.created_at desc
+		LIMIT $3 OFFSET $4;
+	`, otherColumn, ownColumn)
+
+	rows, err := d.db.DB().QueryContext(ctx, query, filter.LoggedUserID, FriendRequestPending, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fr FriendRequestResponse
+		if err := rows.Scan(&pagination.Total, &fr.ID, &fr.CreatedAt,
+			&fr.User.ID, &fr.User.Name, &fr.User.ImageURL, &fr.User.FriendCount); err != nil {
+			return requests, nil, err
+		}
+		requests = append(requests, fr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return requests, nil, err
+	}
+
+	return requests, pagination, nil
+}
+
+func closeRequest(ctx context.Context, tx *sql.Tx, id uint64, status FriendRequestStatus) error {
+	res, err := tx.ExecContext(ctx, `
+			UPDATE friend_requests
+			SET status = $1, responded_at = current_timestamp
+			WHERE id = $2 AND status = $3
+		`, status, id, FriendRequestPending)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -25,3 +25,27 @@ func (p DeleteUserFriendPayload) Validate() error {
 		validation.Field(&p.UserID, validation.Required),
 	)
 }
+
+type RespondFriendRequestPayload struct {
+	LoggedUserID string
+	RequestID    uint64
+}
+
+func (p RespondFriendRequestPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.LoggedUserID, validation.Required.Error(ErrorUnauthorized.Message)),
+		validation.Field(&p.RequestID, validation.Required),
+	)
+}
+
+var (
+	FriendRequestIncoming string = "incoming"
+	FriendRequestOutgoing string = "outgoing"
+)
+
+type ListFriendRequestPayload struct {
+	LoggedUserID string
+	Direction    string
+	Limit        int
+	Offset       int
+}
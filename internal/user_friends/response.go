@@ -1,6 +1,10 @@
 package userfriends
 
-import "github.com/citadel-corp/segokuning-social-app/internal/common/response"
+import (
+	"time"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/response"
+)
 
 type Response struct {
 	Code    int
@@ -11,6 +15,23 @@ type Response struct {
 }
 
 var (
-	SuccessCreateResponse = Response{Code: 200, Message: "Friend added successfully"}
-	SuccessDeleteResponse = Response{Code: 200, Message: "Friend deleted successfully"}
+	SuccessCreateResponse      = Response{Code: 200, Message: "Friend request sent successfully"}
+	SuccessDeleteResponse      = Response{Code: 200, Message: "Friend deleted successfully"}
+	SuccessAcceptResponse      = Response{Code: 200, Message: "Friend added successfully"}
+	SuccessRejectResponse      = Response{Code: 200, Message: "Friend request rejected successfully"}
+	SuccessCancelResponse      = Response{Code: 200, Message: "Friend request cancelled successfully"}
+	SuccessListRequestResponse = Response{Code: 200, Message: "Friend requests fetched successfully"}
 )
+
+type FriendRequestResponse struct {
+	ID        uint64                    `json:"requestId"`
+	User      FriendRequestUserResponse `json:"user"`
+	CreatedAt time.Time                 `json:"createdAt"`
+}
+
+type FriendRequestUserResponse struct {
+	ID          string  `json:"userId"`
+	Name        string  `json:"name"`
+	ImageURL    *string `json:"imageUrl"`
+	FriendCount int     `json:"friendCount"`
+}
@@ -12,6 +12,10 @@ import (
 type Service interface {
 	Create(ctx context.Context, req CreateUserFriendPayload) Response
 	Delete(ctx context.Context, req DeleteUserFriendPayload) Response
+	AcceptRequest(ctx context.Context, req RespondFriendRequestPayload) Response
+	RejectRequest(ctx context.Context, req RespondFriendRequestPayload) Response
+	CancelRequest(ctx context.Context, req RespondFriendRequestPayload) Response
+	ListRequests(ctx context.Context, req ListFriendRequestPayload) Response
 }
 
 type userFriendsService struct {
@@ -26,22 +30,33 @@ func NewService(repository Repository, userRepository user.Repository) Service {
 func (s *userFriendsService) Create(ctx context.Context, req CreateUserFriendPayload) Response {
 	var resp Response
 
-	userFriend := &UserFriends{
-		UserID:   req.LoggedUserID,
-		FriendID: req.UserID,
+	friendRequest := &FriendRequest{
+		SenderID:   req.LoggedUserID,
+		ReceiverID: req.UserID,
 	}
 
-	if userFriend.UserID == userFriend.FriendID {
+	if friendRequest.SenderID == friendRequest.ReceiverID {
 		return ErrCannotAddSelf
 	}
 
-	err := s.repository.AddFriend(ctx, userFriend)
+	// check friendship
+	_, err := s.repository.GetByFriendID(ctx, friendRequest.SenderID, friendRequest.ReceiverID)
+	if err == nil {
+		return ErrFriendAlreadyExists
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	err = s.repository.CreateRequest(ctx, friendRequest)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			switch pgErr.Code {
 			case "23505":
-				return ErrFriendAlreadyExists
+				return ErrFriendRequestAlreadyExists
 			case "23503":
 				return ErrFriendNotExists
 			default:
@@ -99,3 +114,120 @@ func (s *userFriendsService) Delete(ctx context.Context, req DeleteUserFriendPay
 
 	return SuccessDeleteResponse
 }
+
+func (s *userFriendsService) AcceptRequest(ctx context.Context, req RespondFriendRequestPayload) Response {
+	var resp Response
+
+	friendRequest, errResp := s.getPendingRequest(ctx, req.RequestID)
+	if errResp != nil {
+		return *errResp
+	}
+
+	// only the receiver can accept
+	if friendRequest.ReceiverID != req.LoggedUserID {
+		return ErrFriendRequestNotFound
+	}
+
+	err := s.repository.AcceptRequest(ctx, friendRequest)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrFriendRequestNotPending
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrFriendAlreadyExists
+		}
+
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	return SuccessAcceptResponse
+}
+
+func (s *userFriendsService) RejectRequest(ctx context.Context, req RespondFriendRequestPayload) Response {
+	friendRequest, errResp := s.getPendingRequest(ctx, req.RequestID)
+	if errResp != nil {
+		return *errResp
+	}
+
+	// only the receiver can reject
+	if friendRequest.ReceiverID != req.LoggedUserID {
+		return ErrFriendRequestNotFound
+	}
+
+	return s.closeRequest(ctx, friendRequest.ID, FriendRequestRejected, SuccessRejectResponse)
+}
+
+func (s *userFriendsService) CancelRequest(ctx context.Context, req RespondFriendRequestPayload) Response {
+	friendRequest, errResp := s.getPendingRequest(ctx, req.RequestID)
+	if errResp != nil {
+		return *errResp
+	}
+
+	// only the sender can cancel
+	if friendRequest.SenderID != req.LoggedUserID {
+		return ErrFriendRequestNotFound
+	}
+
+	return s.closeRequest(ctx, friendRequest.ID, FriendRequestCancelled, SuccessCancelResponse)
+}
+
+func (s *userFriendsService) ListRequests(ctx context.Context, req ListFriendRequestPayload) Response {
+	var resp Response
+
+	requests, pagination, err := s.repository.ListRequests(ctx, req)
+	if err != nil {
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	if requests == nil {
+		requests = []FriendRequestResponse{}
+	}
+
+	resp = SuccessListRequestResponse
+	resp.Data = requests
+	resp.Meta = pagination
+
+	return resp
+}
+
+func (s *userFriendsService) getPendingRequest(ctx context.Context, requestID uint64) (*FriendRequest, *Response) {
+	friendRequest, err := s.repository.GetRequestByID(ctx, requestID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &ErrFriendRequestNotFound
+		}
+
+		resp := ErrorInternal
+		resp.Error = err.Error()
+		return nil, &resp
+	}
+
+	if friendRequest.Status != FriendRequestPending {
+		return nil, &ErrFriendRequestNotPending
+	}
+
+	return friendRequest, nil
+}
+
+func (s *userFriendsService) closeRequest(ctx context.Context, requestID uint64, status FriendRequestStatus, success Response) Response {
+	var resp Response
+
+	err := s.repository.CloseRequest(ctx, requestID, status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrFriendRequestNotPending
+		}
+
+		resp = ErrorInternal
+		resp.Error = err.Error()
+		return resp
+	}
+
+	return success
+}
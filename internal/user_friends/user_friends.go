@@ -8,3 +8,21 @@ type UserFriends struct {
 	FriendID  string
 	CreatedAt time.Time
 }
+
+type FriendRequestStatus string
+
+var (
+	FriendRequestPending   FriendRequestStatus = "pending"
+	FriendRequestAccepted  FriendRequestStatus = "accepted"
+	FriendRequestRejected  FriendRequestStatus = "rejected"
+	FriendRequestCancelled FriendRequestStatus = "cancelled"
+)
+
+type FriendRequest struct {
+	ID          uint64
+	SenderID    string
+	ReceiverID  string
+	Status      FriendRequestStatus
+	RespondedAt *time.Time
+	CreatedAt   time.Time
+}
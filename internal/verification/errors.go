@@ -0,0 +1,9 @@
+package verification
+
+import "errors"
+
+var (
+	ErrCodeInvalid     = errors.New("verification code is invalid")
+	ErrCodeExpired     = errors.New("verification code has expired")
+	ErrTooManyRequests = errors.New("too many verification codes requested, try again later")
+)
@@ -0,0 +1,111 @@
+package verification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/db"
+)
+
+type Repository interface {
+	Create(ctx context.Context, code *Code) error
+	GetLatest(ctx context.Context, userID string, purpose Purpose) (*Code, error)
+	CountSince(ctx context.Context, userID string, purpose Purpose, since time.Time) (int, error)
+	UseAttempt(ctx context.Context, id string, maxAttempts int) error
+	MarkUsed(ctx context.Context, id string) error
+}
+
+type dbRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+// Create implements Repository.
+func (d *dbRepository) Create(ctx context.Context, code *Code) error {
+	_, err := d.db.DB().ExecContext(ctx, `
+			INSERT INTO verification_codes (
+				id, user_id, purpose, channel, destination, code_hash, expires_at
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7
+			)
+		`, code.ID, code.UserID, code.Purpose, code.Channel, code.Destination, code.CodeHash, code.ExpiresAt)
+	return err
+}
+
+// GetLatest returns the last code issued to the user for the purpose, which is the only one that can be used.
+func (d *dbRepository) GetLatest(ctx context.Context, userID string, purpose Purpose) (*Code, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT id, user_id, purpose, channel, destination, code_hash, attempts, expires_at, used_at, created_at
+		FROM verification_codes
+		WHERE user_id = $1 AND purpose = $2
+		ORDER BY created_at desc
+		LIMIT 1;
+	`, userID, purpose)
+
+	c := &Code{}
+	err := row.Scan(&c.ID, &c.UserID, &c.Purpose, &c.Channel, &c.Destination, &c.CodeHash, &c.Attempts, &c.ExpiresAt, &c.UsedAt, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCodeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// CountSince implements Repository.
+func (d *dbRepository) CountSince(ctx context.Context, userID string, purpose Purpose, since time.Time) (int, error) {
+	row := d.db.DB().QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM verification_codes
+		WHERE user_id = $1 AND purpose = $2 AND created_at >= $3;
+	`, userID, purpose, since)
+
+	var count int
+	err := row.Scan(&count)
+	return count, err
+}
+
+// UseAttempt counts an attempt at the code, it fails with ErrCodeInvalid when the code was used or
+// already had maxAttempts attempts. Checking and counting in one statement keeps concurrent guesses
+// within the limit.
+func (d *dbRepository) UseAttempt(ctx context.Context, id string, maxAttempts int) error {
+	row := d.db.DB().QueryRowContext(ctx, `
+		UPDATE verification_codes
+		SET attempts = attempts + 1
+		WHERE id = $1 AND attempts < $2 AND used_at IS NULL
+		RETURNING attempts;
+	`, id, maxAttempts)
+
+	var attempts int
+	err := row.Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCodeInvalid
+	}
+	return err
+}
+
+// MarkUsed marks the code as used, it fails with ErrCodeInvalid when the code was used concurrently.
+func (d *dbRepository) MarkUsed(ctx context.Context, id string) error {
+	res, err := d.db.DB().ExecContext(ctx, `
+		UPDATE verification_codes
+		SET used_at = current_timestamp
+		WHERE id = $1 AND used_at IS NULL;
+	`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCodeInvalid
+	}
+	return nil
+}
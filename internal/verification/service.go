@@ -0,0 +1,129 @@
+package verification
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/id"
+	"github.com/citadel-corp/segokuning-social-app/internal/common/notifier"
+)
+
+const (
+	codeDigits = 6
+	codeTTL    = 15 * time.Minute
+	// at most maxCodesPerWindow codes can be requested for a purpose within codeRateWindow
+	maxCodesPerWindow = 3
+	codeRateWindow    = time.Hour
+	// a code is burnt after maxCodeAttempts wrong guesses
+	maxCodeAttempts = 5
+)
+
+type Service interface {
+	Issue(ctx context.Context, userID string, purpose Purpose, channel notifier.Channel, destination string) error
+	Verify(ctx context.Context, userID string, purpose Purpose, code string) (string, error)
+}
+
+type verificationService struct {
+	repository Repository
+	notifier   notifier.Notifier
+}
+
+func NewService(repository Repository, notifier notifier.Notifier) Service {
+	return &verificationService{
+		repository: repository,
+		notifier:   notifier,
+	}
+}
+
+// Issue sends a new code to the destination, it replaces the codes issued before for the same purpose.
+func (s *verificationService) Issue(ctx context.Context, userID string, purpose Purpose, channel notifier.Channel, destination string) error {
+	count, err := s.repository.CountSince(ctx, userID, purpose, time.Now().Add(-codeRateWindow))
+	if err != nil {
+		return err
+	}
+	if count >= maxCodesPerWindow {
+		return ErrTooManyRequests
+	}
+
+	plainCode, err := newCode()
+	if err != nil {
+		return err
+	}
+	code := &Code{
+		ID:          id.GenerateStringID(16),
+		UserID:      userID,
+		Purpose:     purpose,
+		Channel:     channel,
+		Destination: destination,
+		ExpiresAt:   time.Now().Add(codeTTL),
+	}
+	code.CodeHash = hashCode(code.ID, plainCode)
+
+	err = s.repository.Create(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Send(ctx, notifier.Message{
+		Channel: channel,
+		To:      destination,
+		Subject: subjects[purpose],
+		Body:    fmt.Sprintf("Your code is %s. It expires in %d minutes.", plainCode, int(codeTTL.Minutes())),
+	})
+}
+
+// Verify consumes the latest code issued to the user for the purpose and returns the destination it was sent to.
+func (s *verificationService) Verify(ctx context.Context, userID string, purpose Purpose, plainCode string) (string, error) {
+	code, err := s.repository.GetLatest(ctx, userID, purpose)
+	if err != nil {
+		return "", err
+	}
+	if code.UsedAt != nil || code.Attempts >= maxCodeAttempts {
+		return "", ErrCodeInvalid
+	}
+	if time.Now().After(code.ExpiresAt) {
+		return "", ErrCodeExpired
+	}
+
+	// every guess is counted before it is compared, so that parallel guesses cannot exceed the limit
+	err = s.repository.UseAttempt(ctx, code.ID, maxCodeAttempts)
+	if err != nil {
+		return "", err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashCode(code.ID, plainCode)), []byte(code.CodeHash)) != 1 {
+		return "", ErrCodeInvalid
+	}
+
+	err = s.repository.MarkUsed(ctx, code.ID)
+	if err != nil {
+		return "", err
+	}
+	return code.Destination, nil
+}
+
+var subjects = map[Purpose]string{
+	PurposePasswordReset: "Reset your password",
+	PurposeVerifyEmail:   "Verify your email",
+	PurposeVerifyPhone:   "Verify your phone number",
+}
+
+func newCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(math.Pow10(codeDigits))))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", codeDigits, n.Int64()), nil
+}
+
+// hashCode salts the code with its ID, the codes themselves are never stored.
+func hashCode(codeID string, plainCode string) string {
+	sum := sha256.Sum256([]byte(codeID + ":" + plainCode))
+	return hex.EncodeToString(sum[:])
+}
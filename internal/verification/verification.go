@@ -0,0 +1,29 @@
+package verification
+
+import (
+	"time"
+
+	"github.com/citadel-corp/segokuning-social-app/internal/common/notifier"
+)
+
+type Purpose string
+
+var (
+	PurposePasswordReset Purpose = "password_reset"
+	PurposeVerifyEmail   Purpose = "verify_email"
+	PurposeVerifyPhone   Purpose = "verify_phone"
+)
+
+// Code is a single-use code sent to a user to prove they own a credential.
+type Code struct {
+	ID          string
+	UserID      string
+	Purpose     Purpose
+	Channel     notifier.Channel
+	Destination string
+	CodeHash    string
+	Attempts    int
+	ExpiresAt   time.Time
+	UsedAt      *time.Time
+	CreatedAt   time.Time
+}
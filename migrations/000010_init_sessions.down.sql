@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS session_refresh_tokens;
+
+DROP INDEX IF EXISTS sessions_user_id;
+
+DROP TABLE IF EXISTS sessions;
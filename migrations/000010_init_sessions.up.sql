@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS
+sessions (
+    id CHAR(16) PRIMARY KEY,
+    user_id CHAR(16) NOT NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT current_timestamp
+);
+
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS fk_user_id;
+ALTER TABLE sessions
+	ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS sessions_user_id
+	ON sessions USING HASH (user_id);
+
+CREATE TABLE IF NOT EXISTS
+session_refresh_tokens (
+    id CHAR(16) PRIMARY KEY,
+    session_id CHAR(16) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT current_timestamp
+);
+
+ALTER TABLE session_refresh_tokens DROP CONSTRAINT IF EXISTS fk_session_id;
+ALTER TABLE session_refresh_tokens
+	ADD CONSTRAINT fk_session_id FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS friend_requests_receiver_id;
+
+DROP INDEX IF EXISTS friend_requests_sender_id;
+
+DROP INDEX IF EXISTS friend_requests_pending_unique;
+
+DROP TABLE IF EXISTS friend_requests;
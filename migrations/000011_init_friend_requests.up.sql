@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS
+friend_requests (
+    id SERIAL PRIMARY KEY,
+    sender_id CHAR(16) NOT NULL,
+    receiver_id CHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    responded_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE friend_requests DROP CONSTRAINT IF EXISTS fk_sender_id;
+ALTER TABLE friend_requests
+	ADD CONSTRAINT fk_sender_id FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE friend_requests DROP CONSTRAINT IF EXISTS fk_receiver_id;
+ALTER TABLE friend_requests
+	ADD CONSTRAINT fk_receiver_id FOREIGN KEY (receiver_id) REFERENCES users(id) ON DELETE CASCADE;
+
+-- only one pending request may exist between two users, regardless of direction
+CREATE UNIQUE INDEX IF NOT EXISTS friend_requests_pending_unique
+	ON friend_requests (LEAST(sender_id, receiver_id), GREATEST(sender_id, receiver_id))
+	WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS friend_requests_sender_id
+	ON friend_requests (sender_id, status);
+
+CREATE INDEX IF NOT EXISTS friend_requests_receiver_id
+	ON friend_requests (receiver_id, status);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS post_revisions_post_id;
+
+DROP TABLE IF EXISTS post_revisions;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS updated_at;
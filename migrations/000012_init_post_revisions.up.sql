@@ -0,0 +1,18 @@
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NULL;
+
+CREATE TABLE IF NOT EXISTS
+post_revisions (
+  id SERIAL PRIMARY KEY,
+  post_id CHAR(16) NOT NULL,
+  content VARCHAR(500) NOT NULL,
+  tags TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE post_revisions DROP CONSTRAINT IF EXISTS fk_post_id;
+ALTER TABLE post_revisions
+	ADD CONSTRAINT fk_post_id FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS post_revisions_post_id
+	ON post_revisions USING HASH (post_id);
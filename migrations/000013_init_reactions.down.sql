@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS comment_reactions;
+
+DROP TABLE IF EXISTS post_reactions;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS
+post_reactions (
+    post_id CHAR(16) NOT NULL,
+    user_id CHAR(16) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    PRIMARY KEY (post_id, user_id)
+);
+
+ALTER TABLE post_reactions DROP CONSTRAINT IF EXISTS fk_post_id;
+ALTER TABLE post_reactions
+	ADD CONSTRAINT fk_post_id FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
+
+ALTER TABLE post_reactions DROP CONSTRAINT IF EXISTS fk_user_id;
+ALTER TABLE post_reactions
+	ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS
+comment_reactions (
+    comment_id INT NOT NULL,
+    user_id CHAR(16) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+ALTER TABLE comment_reactions DROP CONSTRAINT IF EXISTS fk_comment_id;
+ALTER TABLE comment_reactions
+	ADD CONSTRAINT fk_comment_id FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE;
+
+ALTER TABLE comment_reactions DROP CONSTRAINT IF EXISTS fk_user_id;
+ALTER TABLE comment_reactions
+	ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
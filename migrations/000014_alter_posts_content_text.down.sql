@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS posts_content_text;
+CREATE INDEX IF NOT EXISTS posts_content
+	ON posts USING BTREE(content);
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS content_text;
+
+ALTER TABLE post_revisions ALTER COLUMN content TYPE VARCHAR(500);
+ALTER TABLE posts ALTER COLUMN content TYPE VARCHAR(500);
@@ -0,0 +1,11 @@
+ALTER TABLE posts ALTER COLUMN content TYPE TEXT;
+ALTER TABLE post_revisions ALTER COLUMN content TYPE TEXT;
+
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS content_text VARCHAR(500) NOT NULL DEFAULT '';
+UPDATE posts SET content_text = LEFT(regexp_replace(content, '<[^>]*>', '', 'g'), 500);
+ALTER TABLE posts ALTER COLUMN content_text DROP DEFAULT;
+
+DROP INDEX IF EXISTS posts_content;
+CREATE INDEX IF NOT EXISTS posts_content_text
+	ON posts USING BTREE(content_text);
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS comments_post_id_created_at
+	ON comments(post_id, created_at DESC);
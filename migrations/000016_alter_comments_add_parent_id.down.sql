@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS comments_parent_id;
+
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS fk_parent_id;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS parent_id;
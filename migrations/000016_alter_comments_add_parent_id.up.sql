@@ -0,0 +1,11 @@
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS parent_id INT NULL;
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NULL;
+
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS fk_parent_id;
+ALTER TABLE comments
+	ADD CONSTRAINT fk_parent_id FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS comments_parent_id
+	ON comments(parent_id, created_at DESC);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS
+images (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id CHAR(16) NOT NULL,
+    key VARCHAR(64) NOT NULL,
+    content_type VARCHAR(32) NOT NULL,
+    size BIGINT NOT NULL,
+    hash CHAR(64) NOT NULL,
+    referenced_by VARCHAR(64) NULL,
+    created_at TIMESTAMPTZ DEFAULT current_timestamp
+);
+
+ALTER TABLE images DROP CONSTRAINT IF EXISTS fk_user_id;
+ALTER TABLE images
+	ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS images_user_id
+	ON images USING HASH (user_id);
+
+CREATE INDEX IF NOT EXISTS images_referenced_by
+	ON images USING HASH (referenced_by);
+
+CREATE INDEX IF NOT EXISTS images_orphans
+	ON images(created_at) WHERE referenced_by IS NULL;
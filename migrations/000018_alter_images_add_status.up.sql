@@ -0,0 +1,2 @@
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'ready';
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS images_user_id_created_at;
+DROP INDEX IF EXISTS images_user_id_hash;
+
+ALTER TABLE images
+    DROP COLUMN IF EXISTS height;
+ALTER TABLE images
+    DROP COLUMN IF EXISTS width;
+ALTER TABLE images
+    DROP COLUMN IF EXISTS upload_size;
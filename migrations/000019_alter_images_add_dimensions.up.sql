@@ -0,0 +1,12 @@
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS upload_size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS width INT NOT NULL DEFAULT 0;
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS height INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS images_user_id_hash
+	ON images(user_id, hash);
+
+CREATE INDEX IF NOT EXISTS images_user_id_created_at
+	ON images(user_id, created_at DESC);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS
+verification_codes (
+    id CHAR(16) PRIMARY KEY,
+    user_id CHAR(16) NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    channel VARCHAR(16) NOT NULL,
+    destination VARCHAR(255) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT current_timestamp
+);
+
+ALTER TABLE verification_codes DROP CONSTRAINT IF EXISTS fk_user_id;
+ALTER TABLE verification_codes
+	ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS verification_codes_user_id_purpose
+	ON verification_codes(user_id, purpose, created_at DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS phone_number_verified_at;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS email_verified_at;
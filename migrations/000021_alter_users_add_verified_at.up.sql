@@ -0,0 +1,12 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP NULL;
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS phone_number_verified_at TIMESTAMP NULL;
+
+-- the credential a user registered with before verification existed is trusted. A user holding both credentials
+-- linked one of them through the old unverified link endpoint, and since there is no telling which, neither is
+-- trusted: they stay unverified until the user verifies them, and a verified owner can take them over
+UPDATE users SET email_verified_at = created_at
+WHERE email IS NOT NULL AND phone_number IS NULL AND email_verified_at IS NULL;
+UPDATE users SET phone_number_verified_at = created_at
+WHERE phone_number IS NOT NULL AND email IS NULL AND phone_number_verified_at IS NULL;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS
+security_audit_logs (
+    id CHAR(16) PRIMARY KEY,
+    user_id CHAR(16) NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE security_audit_logs DROP CONSTRAINT IF EXISTS fk_user_id;
+ALTER TABLE security_audit_logs
+	ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS security_audit_logs_user_id
+	ON security_audit_logs(user_id, created_at DESC);
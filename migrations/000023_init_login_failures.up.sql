@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS
+login_failures (
+    scope VARCHAR(16) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    blocked_until TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, value)
+);
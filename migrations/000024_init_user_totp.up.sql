@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS
+user_totp (
+    user_id CHAR(16) PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL,
+    confirmed_at TIMESTAMP NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE user_totp DROP CONSTRAINT IF EXISTS fk_user_id;
+ALTER TABLE user_totp
+	ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS
+user_recovery_codes (
+    id CHAR(16) PRIMARY KEY,
+    user_id CHAR(16) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE user_recovery_codes DROP CONSTRAINT IF EXISTS fk_user_id;
+ALTER TABLE user_recovery_codes
+	ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS user_recovery_codes_user_id_code_hash
+	ON user_recovery_codes(user_id, code_hash);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS admin_audit_logs;
+DROP FUNCTION IF EXISTS admin_audit_logs_append_only;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS password_reset_required;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS status;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS role;
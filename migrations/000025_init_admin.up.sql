@@ -0,0 +1,32 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS
+admin_audit_logs (
+    id CHAR(16) PRIMARY KEY,
+    admin_id CHAR(16) NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    target_user_id CHAR(16) NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+-- entries outlive the users they mention, so there are no foreign keys, and they can never be changed
+CREATE OR REPLACE FUNCTION admin_audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'admin_audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS admin_audit_logs_append_only ON admin_audit_logs;
+CREATE TRIGGER admin_audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON admin_audit_logs
+    FOR EACH ROW EXECUTE FUNCTION admin_audit_logs_append_only();
+
+CREATE INDEX IF NOT EXISTS admin_audit_logs_target_user_id
+	ON admin_audit_logs(target_user_id, created_at DESC);
@@ -0,0 +1,45 @@
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP NULL;
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP NULL;
+
+-- reports of a post, comment or user are grouped under its open case, a new case is opened once it was decided
+CREATE TABLE IF NOT EXISTS
+report_cases (
+    id CHAR(16) PRIMARY KEY,
+    target_type VARCHAR(16) NOT NULL,
+    target_id VARCHAR(32) NOT NULL,
+    target_user_id CHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    report_count INT NOT NULL DEFAULT 0,
+    hidden_at TIMESTAMP NULL,
+    decided_by CHAR(16) NULL,
+    decision_note TEXT NOT NULL DEFAULT '',
+    decided_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS report_cases_open_target
+	ON report_cases(target_type, target_id) WHERE status = 'open';
+
+CREATE INDEX IF NOT EXISTS report_cases_status_created_at
+	ON report_cases(status, created_at);
+
+CREATE TABLE IF NOT EXISTS
+reports (
+    id CHAR(16) PRIMARY KEY,
+    case_id CHAR(16) NOT NULL,
+    reporter_id CHAR(16) NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    UNIQUE (case_id, reporter_id)
+);
+
+ALTER TABLE reports DROP CONSTRAINT IF EXISTS fk_case_id;
+ALTER TABLE reports
+	ADD CONSTRAINT fk_case_id FOREIGN KEY (case_id) REFERENCES report_cases(id) ON DELETE CASCADE;
+
+ALTER TABLE reports DROP CONSTRAINT IF EXISTS fk_reporter_id;
+ALTER TABLE reports
+	ADD CONSTRAINT fk_reporter_id FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,15 @@
+-- every upload counted against the daily quota, kept even when the image is deleted or swept
+CREATE TABLE IF NOT EXISTS
+image_uploads (
+    id SERIAL PRIMARY KEY,
+    user_id CHAR(16) NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp
+);
+
+ALTER TABLE image_uploads DROP CONSTRAINT IF EXISTS fk_user_id;
+ALTER TABLE image_uploads
+	ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS image_uploads_user_id_created_at
+	ON image_uploads(user_id, created_at DESC);
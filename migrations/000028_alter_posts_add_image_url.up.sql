@@ -0,0 +1,2 @@
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS image_url VARCHAR NULL;